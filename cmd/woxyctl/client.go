@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//apiPrefix - go-woxy REST API path
+const apiPrefix = "/api/v1"
+
+//Client - go-woxy REST API client
+type Client struct {
+	async  bool
+	http   *http.Client
+	secret string
+	server string
+}
+
+//apiError - JSON error body of go-woxy API
+type apiError struct {
+	ERROR  string
+	STATUS int
+}
+
+//apiPage - Page of a list endpoint
+type apiPage struct {
+	ITEMS  json.RawMessage
+	LIMIT  int
+	OFFSET int
+	TOTAL  int
+}
+
+func newClient(ctx Context) (*Client, error) {
+	secret, err := ctx.secret()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if ctx.INSECURE {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &Client{http: &http.Client{Transport: t}, secret: secret, server: strings.TrimSuffix(ctx.SERVER, "/")}, nil
+}
+
+//request - Send authenticated request to hub
+func (c *Client) request(method string, path string, body string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.server+path, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secret)
+	if c.async {
+		req.Header.Set("Prefer", "respond-async")
+	}
+	return c.http.Do(req)
+}
+
+//do - Send request and decode JSON answer in res, API errors are returned as error
+func (c *Client) do(method string, path string, body string, res interface{}) error {
+	resp, err := c.request(method, apiPrefix+path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e apiError
+		if json.Unmarshal(data, &e) == nil && e.ERROR != "" {
+			return errors.New(e.ERROR)
+		} else if r, ok := com.ParseCommandResult(string(data)); ok {
+			return errors.New(r.Code + " : " + r.Message)
+		}
+		return errors.New(resp.Status)
+	}
+	return json.Unmarshal(data, res)
+}
+
+//list - Get all items of a paginated endpoint
+func (c *Client) list(path string, query url.Values, items interface{}) error {
+	var all []json.RawMessage
+	if query == nil {
+		query = url.Values{}
+	}
+	for offset := 0; ; {
+		query.Set("offset", strconv.Itoa(offset))
+		var p apiPage
+		if err := c.do("GET", path+"?"+query.Encode(), "", &p); err != nil {
+			return err
+		}
+		var page []json.RawMessage
+		if err := json.Unmarshal(p.ITEMS, &page); err != nil {
+			return err
+		}
+		all = append(all, page...)
+		offset += len(page)
+		if len(page) == 0 || offset >= p.TOTAL {
+			break
+		}
+	}
+
+	b, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, items)
+}
+
+//follow - Print module log lines streamed by hub until connection closes
+func (c *Client) follow(query url.Values, out io.Writer) error {
+	resp, err := c.request("GET", "/logs/stream?"+query.Encode(), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(strings.TrimSpace(resp.Status + " " + string(b)))
+	}
+
+	s := bufio.NewScanner(resp.Body)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		if l := s.Text(); strings.HasPrefix(l, "data:") {
+			io.WriteString(out, strings.TrimPrefix(l, "data:")+"\n")
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+//Context - go-woxy hub woxyctl talks to, SECRET is server secret or SECRET_FILE the hub generated .secret file
+type Context struct {
+	INSECURE    bool
+	SECRET      string
+	SECRET_FILE string
+	SERVER      string
+}
+
+//ContextFile - Contexts of woxyctl, saved in ~/.woxyctl.yml or WOXYCTL_CONFIG
+type ContextFile struct {
+	CONTEXTS map[string]Context
+	CURRENT  string
+	path     string
+}
+
+//contextPath - Get context file path
+func contextPath() string {
+	if p := os.Getenv("WOXYCTL_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".woxyctl.yml"
+	}
+	return filepath.Join(home, ".woxyctl.yml")
+}
+
+//loadContexts - Read context file, empty when it does not exist yet
+func loadContexts() (*ContextFile, error) {
+	cf := ContextFile{CONTEXTS: map[string]Context{}, path: contextPath()}
+	data, err := ioutil.ReadFile(cf.path)
+	if os.IsNotExist(err) {
+		return &cf, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		return nil, errors.New("parsing " + cf.path + " : " + err.Error())
+	}
+	if cf.CONTEXTS == nil {
+		cf.CONTEXTS = map[string]Context{}
+	}
+	return &cf, nil
+}
+
+//save - Write context file, readable by user only as it holds secrets
+func (cf *ContextFile) save() error {
+	data, err := yaml.Marshal(cf)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cf.path, data, 0600)
+}
+
+//get - Get named context, or current one when name is empty
+func (cf *ContextFile) get(name string) (Context, error) {
+	if name == "" {
+		name = cf.CURRENT
+	}
+	if name == "" {
+		return Context{}, errors.New("no context set, run : woxyctl context set <name> -server <url> -secret-file <path>")
+	}
+	c, ok := cf.CONTEXTS[name]
+	if !ok {
+		return c, errors.New("context " + name + " not found in " + cf.path)
+	}
+	return c, nil
+}
+
+//secret - Get server secret, hashing generated secret file like modules do
+func (c Context) secret() (string, error) {
+	if c.SECRET != "" || c.SECRET_FILE == "" {
+		return c.SECRET, nil
+	}
+	b, err := ioutil.ReadFile(c.SECRET_FILE)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return base64.URLEncoding.EncodeToString(h[:]), nil
+}
+
+//names - Get context names sorted
+func (cf *ContextFile) names() []string {
+	var res []string
+	for n := range cf.CONTEXTS {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,651 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Wariie/go-woxy/com"
+	"github.com/Wariie/go-woxy/core"
+)
+
+const usage = `woxyctl - go-woxy hub command-line client
+
+Usage : woxyctl [-context name] [-o table|json] <command> [arguments]
+
+Commands :
+  list [-l selector]            List modules, matching label selector
+  status <module>               Show module state, binding and health
+  logs <module> [-f] [-tail n] [-since d] [-grep re] [-level l] [-request-id id]
+                                Show module log, -f follows it
+  start|stop|restart <module> [-async]
+                                Start, stop or restart module
+  kill|rollback <module> [-async]
+                                Kill module process group, or restart it on previous commit
+  deploy <module> [-async]      Deploy module ref
+  cmd <module> <command> [-async] [name=value ...] [content]
+                                Run core or module custom command with arguments
+  jobs                          List jobs of commands run with -async
+  job <id> [-wait d]            Show job progress and result, -wait waits for it to finish
+  cancel <id>                   Cancel job
+  describe <module> [command]   List commands of module, or show command parameters
+  config validate [file]        Check go-woxy config file (default : cfg.yml)
+  context list                  List contexts
+  context use <name>            Set current context
+  context set <name> -server url [-secret secret | -secret-file path] [-insecure]
+                                Add or update context
+`
+
+//moduleSummary - Module as answered by hub API
+type moduleSummary struct {
+	ADDRESS string
+	COMMIT  string
+	HEALTH  struct {
+		LAST_ERROR string
+		LAST_PROBE time.Time
+		LIVE       bool
+		READY      bool
+		RESTARTS   int
+	}
+	LABELS   map[string]string
+	NAME     string
+	PINNED   string
+	PORT     string
+	PROTOCOL string
+	READY    bool
+	ROUTES   []struct {
+		FROM string
+		TO   string
+	}
+	SOURCE string
+	STATE  string
+	TYPES  string
+}
+
+//deployment - Deployment as answered by hub API
+type deployment struct {
+	ID      string
+	MODULES []string
+	STATUS  string
+}
+
+//job - Job as answered by hub API
+type job struct {
+	COMMAND  string
+	CREATED  time.Time
+	FINISHED time.Time
+	ID       string
+	MODULES  []string
+	RESULT   *com.CommandResult
+	STARTED  time.Time
+	STATE    string
+	STEPS    []struct {
+		MESSAGE string
+		TIME    time.Time
+	}
+}
+
+func main() {
+	flags := flag.NewFlagSet("woxyctl", flag.ExitOnError)
+	ctxName := flags.String("context", "", "context to use (default : current context)")
+	output := flags.String("o", "table", "output format : table or json")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fail(errors.New("unknown output format " + *output))
+	}
+
+	if err := run(*ctxName, *output, flags.Arg(0), flags.Args()[1:]); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "woxyctl :", err)
+	os.Exit(1)
+}
+
+func run(ctxName string, output string, command string, args []string) error {
+	switch command {
+	case "config":
+		return configCommand(args)
+	case "context":
+		return contextCommand(output, args)
+	case "help":
+		fmt.Print(usage)
+		return nil
+	}
+
+	cf, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	ctx, err := cf.get(ctxName)
+	if err != nil {
+		return err
+	}
+	c, err := newClient(ctx)
+	if err != nil {
+		return err
+	}
+	if command != "logs" {
+		args, c.async = asyncArg(args)
+	}
+	if c.async {
+		return asyncCommand(c, output, command, args)
+	}
+
+	switch command {
+	case "list":
+		flags := flag.NewFlagSet("list", flag.ExitOnError)
+		selector := flags.String("l", "", "label selector (example : env=prod,tier!=batch)")
+		flags.Parse(args)
+
+		var mods []moduleSummary
+		if err := c.list("/modules", url.Values{"selector": {*selector}}, &mods); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(mods)
+		}
+		w := table("NAME", "STATE", "READY", "TYPES", "BINDING", "COMMIT")
+		for _, m := range mods {
+			row(w, m.NAME, m.STATE, strconv.FormatBool(m.READY), m.TYPES, m.binding(), short(m.COMMIT))
+		}
+		return w.Flush()
+
+	case "status":
+		name, err := moduleArg(command, args)
+		if err != nil {
+			return err
+		}
+		var m moduleSummary
+		if err := c.do("GET", "/modules/"+url.PathEscape(name), "", &m); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(m)
+		}
+		return m.print()
+
+	case "logs":
+		return logsCommand(c, output, args)
+
+	case "start", "stop", "restart", "kill", "rollback":
+		name, err := moduleArg(command, args)
+		if err != nil {
+			return err
+		}
+		var m moduleSummary
+		if err := c.do("POST", "/modules/"+url.PathEscape(name)+"/"+command, "", &m); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(m)
+		}
+		fmt.Println(m.NAME, ":", m.STATE)
+		return nil
+
+	case "deploy":
+		name, err := moduleArg(command, args)
+		if err != nil {
+			return err
+		}
+		var d deployment
+		if err := c.do("POST", "/modules/"+url.PathEscape(name)+"/deploy", "", &d); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(d)
+		}
+		fmt.Println("Deployment", d.ID, "of", strings.Join(d.MODULES, ", "), ":", d.STATUS)
+		return nil
+
+	case "cmd":
+		if len(args) < 2 {
+			return errors.New("usage : woxyctl cmd <module> <command> [name=value ...] [content]")
+		}
+		q, content := commandArgs(args[2:])
+		path := "/modules/" + url.PathEscape(args[0]) + "/commands/" + url.PathEscape(args[1])
+		if len(q) > 0 {
+			path += "?" + q.Encode()
+		}
+
+		var r com.CommandResult
+		if err := c.do("POST", path, content, &r); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(r)
+		}
+		fmt.Println(r.Text())
+		return nil
+
+	case "describe":
+		if len(args) < 1 || len(args) > 2 {
+			return errors.New("usage : woxyctl describe <module> [command]")
+		}
+		path := "/modules/" + url.PathEscape(args[0]) + "/commands"
+		if len(args) == 1 {
+			var specs []com.CommandSpec
+			if err := c.do("GET", path, "", &specs); err != nil {
+				return err
+			}
+			if output == "json" {
+				return printJSON(specs)
+			}
+			w := table("COMMAND", "PARAMETERS", "DESCRIPTION")
+			for _, s := range specs {
+				var params []string
+				for _, p := range s.Params {
+					params = append(params, p.Name)
+				}
+				row(w, s.Name, strings.Join(params, ","), s.Description)
+			}
+			return w.Flush()
+		}
+
+		var spec com.CommandSpec
+		if err := c.do("GET", path+"/"+url.PathEscape(args[1]), "", &spec); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(spec)
+		}
+		fmt.Println(spec.Name, ":", spec.Description)
+		w := table("PARAMETER", "TYPE", "REQUIRED", "DEFAULT", "VALUES", "DESCRIPTION")
+		for _, p := range spec.Params {
+			row(w, p.Name, p.Type, strconv.FormatBool(p.Required), p.Default, strings.Join(p.Enum, ","), p.Description)
+		}
+		return w.Flush()
+
+	case "jobs":
+		var jobs []job
+		if err := c.list("/jobs", nil, &jobs); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(jobs)
+		}
+		w := table("ID", "COMMAND", "MODULES", "STATE", "CREATED")
+		for _, j := range jobs {
+			row(w, j.ID, j.COMMAND, strings.Join(j.MODULES, ","), j.STATE, j.CREATED.Format(time.RFC3339))
+		}
+		return w.Flush()
+
+	case "job":
+		flags := flag.NewFlagSet("job", flag.ExitOnError)
+		wait := flags.Duration("wait", 0, "wait for job to finish (long polling)")
+		id := ""
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			id, args = args[0], args[1:]
+		}
+		flags.Parse(args)
+		if id == "" && flags.NArg() > 0 {
+			id = flags.Arg(0)
+		}
+		if id == "" {
+			return errors.New("usage : woxyctl job <id> [-wait d]")
+		}
+		return waitJob(c, output, id, *wait)
+
+	case "cancel":
+		id, err := moduleArg(command, args)
+		if err != nil {
+			return errors.New("usage : woxyctl cancel <id>")
+		}
+		var j job
+		if err := c.do("DELETE", "/jobs/"+url.PathEscape(id), "", &j); err != nil {
+			return err
+		}
+		if output == "json" {
+			return printJSON(j)
+		}
+		fmt.Println("Job", j.ID, ": canceling")
+		return nil
+	}
+	return errors.New("unknown command " + command + ", run woxyctl help")
+}
+
+//asyncArg - Remove -async flag from arguments
+func asyncArg(args []string) ([]string, bool) {
+	var res []string
+	async := false
+	for _, a := range args {
+		if a == "-async" || a == "--async" {
+			async = true
+		} else {
+			res = append(res, a)
+		}
+	}
+	return res, async
+}
+
+//asyncCommand - Run module command as job and print it
+func asyncCommand(c *Client, output string, command string, args []string) error {
+	var path, body string
+	switch command {
+	case "start", "stop", "restart", "kill", "rollback", "deploy":
+		name, err := moduleArg(command, args)
+		if err != nil {
+			return err
+		}
+		path = "/modules/" + url.PathEscape(name) + "/" + command
+	case "cmd":
+		if len(args) < 2 {
+			return errors.New("usage : woxyctl cmd <module> <command> [-async] [name=value ...] [content]")
+		}
+		q, content := commandArgs(args[2:])
+		path, body = "/modules/"+url.PathEscape(args[0])+"/commands/"+url.PathEscape(args[1]), content
+		if len(q) > 0 {
+			path += "?" + q.Encode()
+		}
+	default:
+		return errors.New("-async is not supported by " + command)
+	}
+
+	var j job
+	if command == "cmd" {
+		var r com.CommandResult
+		if err := c.do("POST", path, body, &r); err != nil {
+			return err
+		} else if err := json.Unmarshal(r.Data, &j); err != nil {
+			return err
+		}
+	} else if err := c.do("POST", path, body, &j); err != nil {
+		return err
+	}
+	if output == "json" {
+		return printJSON(j)
+	}
+	fmt.Println("Job", j.ID, ":", j.STATE, "- run : woxyctl job", j.ID, "-wait 1m")
+	return nil
+}
+
+//waitJob - Print job, waiting for it to finish until wait is elapsed
+func waitJob(c *Client, output string, id string, wait time.Duration) error {
+	var j job
+	for end := time.Now().Add(wait); ; {
+		q := url.Values{}
+		if left := time.Until(end); left > 0 {
+			q.Set("wait", left.Round(time.Second).String())
+		}
+		if err := c.do("GET", "/jobs/"+url.PathEscape(id)+"?"+q.Encode(), "", &j); err != nil {
+			return err
+		}
+		//HUB WAITS AT MOST 1 MINUTE PER REQUEST
+		if j.finished() || !time.Now().Before(end) {
+			break
+		}
+	}
+	if output == "json" {
+		return printJSON(j)
+	}
+	return j.print()
+}
+
+func (j job) finished() bool {
+	return j.STATE == "SUCCEEDED" || j.STATE == "FAILED" || j.STATE == "CANCELED"
+}
+
+func (j job) print() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	row(w, "ID:", j.ID)
+	row(w, "Command:", j.COMMAND)
+	row(w, "Modules:", strings.Join(j.MODULES, ", "))
+	row(w, "State:", j.STATE)
+	row(w, "Created:", j.CREATED.Format(time.RFC3339))
+	for _, s := range j.STEPS {
+		row(w, "Step:", s.TIME.Format("15:04:05")+" "+s.MESSAGE)
+	}
+	if j.RESULT != nil {
+		row(w, "Result:", j.RESULT.Code+" "+j.RESULT.Text())
+	}
+	return w.Flush()
+}
+
+//commandArgs - Split command arguments in name=value arguments and content
+func commandArgs(args []string) (url.Values, string) {
+	q, content := url.Values{}, []string{}
+	for _, a := range args {
+		if kv := strings.SplitN(a, "=", 2); len(kv) == 2 && kv[0] != "" {
+			q.Set(kv[0], kv[1])
+		} else {
+			content = append(content, a)
+		}
+	}
+	return q, strings.Join(content, " ")
+}
+
+func logsCommand(c *Client, output string, args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ExitOnError)
+	follow := flags.Bool("f", false, "follow log")
+	tail := flags.Int("tail", 0, "last lines only")
+	since := flags.String("since", "", "lines since duration or RFC3339 time")
+	grep := flags.String("grep", "", "lines matching regular expression")
+	level := flags.String("level", "", "lines at or above level")
+	requestID := flags.String("request-id", "", "lines of a request")
+
+	//MODULE NAME MAY COME BEFORE FLAGS
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if name == "" && flags.NArg() > 0 {
+		name = flags.Arg(0)
+	}
+	if name == "" {
+		return errors.New("usage : woxyctl logs <module> [flags]")
+	}
+
+	q := url.Values{}
+	set := func(k string, v string) {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if *tail > 0 {
+		q.Set("tail", strconv.Itoa(*tail))
+	}
+	set("since", *since)
+	set("grep", *grep)
+	set("level", *level)
+	set("request_id", *requestID)
+
+	if *follow {
+		q.Set("modules", name)
+		if output == "json" {
+			q.Set("format", "json")
+		}
+		return c.follow(q, os.Stdout)
+	}
+
+	var lines []json.RawMessage
+	if err := c.list("/modules/"+url.PathEscape(name)+"/logs", q, &lines); err != nil {
+		return err
+	}
+	if output == "json" {
+		return printJSON(lines)
+	}
+	for _, raw := range lines {
+		var l struct {
+			Instance string
+			Level    string
+			Module   string
+			Stream   string
+			Text     string
+			Time     time.Time
+		}
+		if err := json.Unmarshal(raw, &l); err != nil {
+			return err
+		}
+		fmt.Println(l.Time.Format("2006-01-02T15:04:05.000Z07:00") + " [" + l.Module + "/" + l.Instance + "] " + l.Stream + " " + l.Level + " " + l.Text)
+	}
+	return nil
+}
+
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return errors.New("usage : woxyctl config validate [file]")
+	}
+	path := "cfg.yml"
+	if len(args) > 1 {
+		path = args[1]
+	}
+	if err := core.ValidateConfig(path); err != nil {
+		return errors.New(path + " : " + err.Error())
+	}
+	fmt.Println(path, ": valid")
+	return nil
+}
+
+func contextCommand(output string, args []string) error {
+	cf, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		if output == "json" {
+			return printJSON(cf.names())
+		}
+		w := table("CURRENT", "NAME", "SERVER")
+		for _, n := range cf.names() {
+			current := ""
+			if n == cf.CURRENT {
+				current = "*"
+			}
+			row(w, current, n, cf.CONTEXTS[n].SERVER)
+		}
+		return w.Flush()
+
+	case "use":
+		if len(args) != 2 {
+			return errors.New("usage : woxyctl context use <name>")
+		}
+		if _, ok := cf.CONTEXTS[args[1]]; !ok {
+			return errors.New("context " + args[1] + " not found")
+		}
+		cf.CURRENT = args[1]
+		return cf.save()
+
+	case "set":
+		if len(args) < 2 {
+			return errors.New("usage : woxyctl context set <name> -server url [-secret secret | -secret-file path] [-insecure]")
+		}
+		ctx := cf.CONTEXTS[args[1]]
+		flags := flag.NewFlagSet("context set", flag.ExitOnError)
+		flags.StringVar(&ctx.SERVER, "server", ctx.SERVER, "hub URL (example : http://127.0.0.1:2000)")
+		flags.StringVar(&ctx.SECRET, "secret", ctx.SECRET, "hub secret")
+		flags.StringVar(&ctx.SECRET_FILE, "secret-file", ctx.SECRET_FILE, "secret file generated by hub")
+		flags.BoolVar(&ctx.INSECURE, "insecure", ctx.INSECURE, "skip TLS certificate verification")
+		flags.Parse(args[2:])
+		if ctx.SERVER == "" {
+			return errors.New("context " + args[1] + " without server")
+		}
+		if ctx.SECRET_FILE != "" {
+			if ctx.SECRET_FILE, err = filepath.Abs(ctx.SECRET_FILE); err != nil {
+				return err
+			}
+		}
+
+		cf.CONTEXTS[args[1]] = ctx
+		if cf.CURRENT == "" {
+			cf.CURRENT = args[1]
+		}
+		return cf.save()
+	}
+	return errors.New("unknown context command " + args[0])
+}
+
+func moduleArg(command string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage : woxyctl " + command + " <module>")
+	}
+	return args[0], nil
+}
+
+func (m moduleSummary) binding() string {
+	if m.PORT == "" {
+		return ""
+	}
+	return m.PROTOCOL + "://" + m.ADDRESS + ":" + m.PORT
+}
+
+func (m moduleSummary) labels() string {
+	var res []string
+	for k, v := range m.LABELS {
+		res = append(res, k+"="+v)
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+func (m moduleSummary) print() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	row(w, "Name:", m.NAME)
+	row(w, "State:", m.STATE)
+	row(w, "Ready:", strconv.FormatBool(m.READY))
+	row(w, "Live:", strconv.FormatBool(m.HEALTH.LIVE))
+	row(w, "Restarts:", strconv.Itoa(m.HEALTH.RESTARTS))
+	if m.HEALTH.LAST_ERROR != "" {
+		row(w, "Last probe error:", m.HEALTH.LAST_ERROR)
+	}
+	row(w, "Types:", m.TYPES)
+	row(w, "Labels:", m.labels())
+	row(w, "Binding:", m.binding())
+	for _, r := range m.ROUTES {
+		row(w, "Route:", r.FROM+" => "+r.TO)
+	}
+	row(w, "Source:", m.SOURCE)
+	row(w, "Commit:", m.COMMIT)
+	if m.PINNED != "" {
+		row(w, "Pinned:", m.PINNED+" (rollback, released by next deploy)")
+	}
+	return w.Flush()
+}
+
+func table(headers ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	row(w, headers...)
+	return w
+}
+
+func row(w *tabwriter.Writer, cells ...string) {
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
+
+func short(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+func printJSON(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		query   string
+		content string
+	}{
+		{nil, "", ""},
+		{[]string{"tail=10", "level=warn"}, "level=warn&tail=10", ""},
+		{[]string{"hello", "world"}, "", "hello world"},
+		{[]string{"grep=a=b", "some", "text"}, "grep=a%3Db", "some text"},
+		{[]string{"=x", "tail="}, "tail=", "=x"},
+	}
+	for _, tt := range tests {
+		q, content := commandArgs(tt.args)
+		if q.Encode() != tt.query || content != tt.content {
+			t.Errorf("commandArgs(%q) = %q, %q, want %q, %q", tt.args, q.Encode(), content, tt.query, tt.content)
+		}
+	}
+}
+
+func TestAsyncArg(t *testing.T) {
+	tests := []struct {
+		args  []string
+		rest  string
+		async bool
+	}{
+		{[]string{"m"}, "m", false},
+		{[]string{"m", "-async"}, "m", true},
+		{[]string{"--async", "m", "Log", "tail=1"}, "m,Log,tail=1", true},
+	}
+	for _, tt := range tests {
+		rest, async := asyncArg(tt.args)
+		if strings.Join(rest, ",") != tt.rest || async != tt.async {
+			t.Errorf("asyncArg(%q) = %q, %v, want %s, %v", tt.args, rest, async, tt.rest, tt.async)
+		}
+	}
+}
+
+func TestRunRequests(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		query  string
+		body   string
+		prefer string
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ERROR":"Secret not matching with server","STATUS":401}`))
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		got = append(got, request{r.Method, r.URL.EscapedPath(), r.URL.RawQuery, string(b), r.Header.Get("Prefer")})
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ERROR":"module missing not found","STATUS":404}`))
+		case strings.HasSuffix(r.URL.Path, "/commands/Start"):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"Code":"CONFLICT","Command":"Start","Message":"Module already ONLINE","Status":"error"}`))
+		default:
+			//ANSWER FITS PAGES, JOBS AND COMMAND RESULTS
+			w.Write([]byte(`{"ITEMS":[],"TOTAL":0,"ID":"j1","STATE":"SUCCEEDED","Code":"OK","Status":"success","Data":{"ID":"j1"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "woxyctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("WOXYCTL_CONFIG", filepath.Join(dir, "contexts.yml"))
+	defer os.Unsetenv("WOXYCTL_CONFIG")
+	if err := contextCommand("table", []string{"set", "test", "-server", srv.URL + "/", "-secret", "s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := contextCommand("table", []string{"set", "other", "-server", srv.URL, "-secret", "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	//OUTPUTS ARE NOT CHECKED
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	defer func() { os.Stdout = stdout }()
+
+	tests := []struct {
+		context string
+		args    []string
+		want    request
+		wantErr string
+	}{
+		{"", []string{"list", "-l", "env=prod"}, request{"GET", "/api/v1/modules", "offset=0&selector=env%3Dprod", "", ""}, ""},
+		{"", []string{"status", "m"}, request{"GET", "/api/v1/modules/m", "", "", ""}, ""},
+		{"", []string{"status", "a/b"}, request{"GET", "/api/v1/modules/a%2Fb", "", "", ""}, ""},
+		{"", []string{"restart", "m"}, request{"POST", "/api/v1/modules/m/restart", "", "", ""}, ""},
+		{"", []string{"start", "m", "-async"}, request{"POST", "/api/v1/modules/m/start", "", "", "respond-async"}, ""},
+		{"", []string{"deploy", "m"}, request{"POST", "/api/v1/modules/m/deploy", "", "", ""}, ""},
+		{"", []string{"cmd", "m", "Log", "tail=5", "hello", "world"}, request{"POST", "/api/v1/modules/m/commands/Log", "tail=5", "hello world", ""}, ""},
+		{"", []string{"cmd", "m", "Log", "-async", "tail=5"}, request{"POST", "/api/v1/modules/m/commands/Log", "tail=5", "", "respond-async"}, ""},
+		{"", []string{"logs", "m", "-tail", "3", "-grep", "err"}, request{"GET", "/api/v1/modules/m/logs", "grep=err&offset=0&tail=3", "", ""}, ""},
+		{"", []string{"describe", "m", "Log"}, request{"GET", "/api/v1/modules/m/commands/Log", "", "", ""}, ""},
+		{"", []string{"job", "j1"}, request{"GET", "/api/v1/jobs/j1", "", "", ""}, ""},
+		{"", []string{"cancel", "j1"}, request{"DELETE", "/api/v1/jobs/j1", "", "", ""}, ""},
+		{"", []string{"status", "missing"}, request{"GET", "/api/v1/modules/missing", "", "", ""}, "module missing not found"},
+		{"", []string{"cmd", "m", "Start"}, request{"POST", "/api/v1/modules/m/commands/Start", "", "", ""}, "CONFLICT : Module already ONLINE"},
+		{"other", []string{"status", "m"}, request{}, "Secret not matching with server"},
+		{"", []string{"start"}, request{}, "usage : woxyctl start <module>"},
+		{"", []string{"jobs", "-async"}, request{}, "-async is not supported by jobs"},
+		{"", []string{"nope"}, request{}, "unknown command nope"},
+		{"unknown", []string{"list"}, request{}, "context unknown not found"},
+	}
+	for _, tt := range tests {
+		got = nil
+		err := run(tt.context, "json", tt.args[0], tt.args[1:])
+		name := strings.Join(tt.args, " ")
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s : unexpected error %v", name, err)
+		} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s : error = %v, want %q", name, err, tt.wantErr)
+		}
+
+		if tt.want.method == "" {
+			if len(got) != 0 {
+				t.Errorf("%s : requests %+v, want none", name, got)
+			}
+		} else if len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%s : requests %+v, want %+v", name, got, tt.want)
+		}
+	}
+}
+
+func TestContextSecretFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "woxy-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("generated")
+	f.Close()
+
+	tests := []struct {
+		ctx  Context
+		want string
+	}{
+		{Context{SECRET: "s"}, "s"},
+		{Context{SECRET: "s", SECRET_FILE: f.Name()}, "s"},
+		//SHA-256 OF FILE, URL BASE64 ENCODED LIKE MODULES DO
+		{Context{SECRET_FILE: f.Name()}, "4MuAClzNpMsbKteZDeCCqqHkDncYmMC8so_LI8Jh5CI="},
+	}
+	for _, tt := range tests {
+		if got, err := tt.ctx.secret(); err != nil || got != tt.want {
+			t.Errorf("secret of %+v = %s, %v, want %s", tt.ctx, got, err, tt.want)
+		}
+	}
+	if _, err := (Context{SECRET_FILE: f.Name() + ".missing"}).secret(); err == nil {
+		t.Errorf("secret of missing file : no error")
+	}
+}
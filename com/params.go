@@ -0,0 +1,119 @@
+package com
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Parameter types
+const (
+	ParamBool     = "bool"
+	ParamDuration = "duration"
+	ParamInt      = "int"
+	ParamString   = "string"
+)
+
+/*Param - Named and typed command parameter*/
+type Param struct {
+	Default     string   `json:",omitempty"`
+	Description string   `json:",omitempty"`
+	Enum        []string `json:",omitempty"`
+	Name        string
+	Required    bool `json:",omitempty"`
+	Type        string
+}
+
+/*CommandSpec - Command description and parameters*/
+type CommandSpec struct {
+	Description string `json:",omitempty"`
+	Name        string
+	Params      []Param `json:",omitempty"`
+}
+
+/*Args - Command arguments by parameter name*/
+type Args map[string]string
+
+//check - Check value matches parameter type and allowed values
+func (p Param) check(v string) error {
+	var err error
+	switch p.Type {
+	case ParamBool:
+		_, err = strconv.ParseBool(v)
+	case ParamDuration:
+		_, err = time.ParseDuration(v)
+	case ParamInt:
+		_, err = strconv.Atoi(v)
+	case ParamString, "":
+	default:
+		return errors.New("unknown type " + p.Type)
+	}
+	if err != nil {
+		return errors.New(strconv.Quote(v) + " is not a " + p.Type)
+	}
+
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if e == v {
+				return nil
+			}
+		}
+		return errors.New(strconv.Quote(v) + " is not one of " + strings.Join(p.Enum, ", "))
+	}
+	return nil
+}
+
+//Validate - Check args against command parameters and add defaults, errors are BAD_REQUEST CommandError
+func (cs CommandSpec) Validate(args Args) (Args, error) {
+	params := map[string]Param{}
+	for _, p := range cs.Params {
+		params[p.Name] = p
+	}
+
+	res := Args{}
+	for k, v := range args {
+		p, ok := params[k]
+		if !ok {
+			return nil, NewCommandError(CodeBadRequest, "unknown parameter "+k+" of command "+cs.Name)
+		}
+		if err := p.check(v); err != nil {
+			return nil, NewCommandError(CodeBadRequest, "parameter "+k+" of command "+cs.Name+" : "+err.Error())
+		}
+		res[k] = v
+	}
+
+	for _, p := range cs.Params {
+		if _, ok := res[p.Name]; ok {
+			continue
+		} else if p.Required {
+			return nil, NewCommandError(CodeBadRequest, "missing parameter "+p.Name+" of command "+cs.Name)
+		} else if p.Default != "" {
+			res[p.Name] = p.Default
+		}
+	}
+	return res, nil
+}
+
+//String - Get string argument
+func (a Args) String(name string) string {
+	return a[name]
+}
+
+//Bool - Get bool argument, false when not set
+func (a Args) Bool(name string) bool {
+	b, _ := strconv.ParseBool(a[name])
+	return b
+}
+
+//Duration - Get duration argument, 0 when not set
+func (a Args) Duration(name string) time.Duration {
+	d, _ := time.ParseDuration(a[name])
+	return d
+}
+
+//Int - Get int argument, 0 when not set
+func (a Args) Int(name string) int {
+	i, _ := strconv.Atoi(a[name])
+	return i
+}
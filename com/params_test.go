@@ -0,0 +1,73 @@
+package com
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCommandSpecValidate(t *testing.T) {
+	spec := CommandSpec{Name: "Log", Params: []Param{
+		{Name: "tail", Type: ParamInt, Default: "100"},
+		{Name: "follow", Type: ParamBool},
+		{Name: "since", Type: ParamDuration},
+		{Name: "level", Type: ParamString, Enum: []string{"info", "warn", "error"}},
+		{Name: "module", Required: true},
+	}}
+
+	tests := []struct {
+		name    string
+		args    Args
+		want    Args
+		wantErr string
+	}{
+		{"defaults", Args{"module": "m"}, Args{"module": "m", "tail": "100"}, ""},
+		{"all set", Args{"module": "m", "tail": "5", "follow": "true", "since": "10m", "level": "warn"}, Args{"module": "m", "tail": "5", "follow": "true", "since": "10m", "level": "warn"}, ""},
+		{"missing required", Args{"tail": "5"}, nil, "missing parameter module of command Log"},
+		{"unknown parameter", Args{"module": "m", "grep": "x"}, nil, "unknown parameter grep of command Log"},
+		{"bad int", Args{"module": "m", "tail": "ten"}, nil, `parameter tail of command Log : "ten" is not a int`},
+		{"bad bool", Args{"module": "m", "follow": "maybe"}, nil, `parameter follow of command Log : "maybe" is not a bool`},
+		{"bad duration", Args{"module": "m", "since": "10"}, nil, `parameter since of command Log : "10" is not a duration`},
+		{"not in enum", Args{"module": "m", "level": "debug"}, nil, `parameter level of command Log : "debug" is not one of info, warn, error`},
+	}
+	for _, tt := range tests {
+		got, err := spec.Validate(tt.args)
+		if tt.wantErr != "" {
+			ce, ok := err.(*CommandError)
+			if !ok || ce.Code != CodeBadRequest || ce.Message != tt.wantErr {
+				t.Errorf("%s : error = %v, want %s %q", tt.name, err, CodeBadRequest, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s : Validate = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := (CommandSpec{Name: "X", Params: []Param{{Name: "p", Type: "float"}}}).Validate(Args{"p": "1"}); err == nil {
+		t.Errorf("unknown parameter type accepted")
+	}
+}
+
+func TestArgs(t *testing.T) {
+	a := Args{"s": "v", "b": "true", "d": "1m30s", "i": "42", "bad": "x"}
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"String", a.String("s"), "v"},
+		{"String missing", a.String("none"), ""},
+		{"Bool", a.Bool("b"), true},
+		{"Bool invalid", a.Bool("bad"), false},
+		{"Duration", a.Duration("d"), 90 * time.Second},
+		{"Duration missing", a.Duration("none"), time.Duration(0)},
+		{"Int", a.Int("i"), 42},
+		{"Int invalid", a.Int("bad"), 0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package com
+
+import (
+	"net/url"
+	"strings"
+)
+
+//RedactQuery - Hide values of secret and token-like query parameters, order of parameters is kept
+func RedactQuery(raw string) string {
+	parts := strings.Split(raw, "&")
+	for i, p := range parts {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 && kv[1] != "" && sensitiveParam(kv[0]) {
+			parts[i] = kv[0] + "=REDACTED"
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+//sensitiveParam - Check query parameter may hold a credential
+func sensitiveParam(name string) bool {
+	if n, err := url.QueryUnescape(name); err == nil {
+		name = n
+	}
+	name = strings.ToLower(name)
+	for _, s := range []string{"auth", "key", "passw", "secret", "signature", "token"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return name == "sig"
+}
@@ -0,0 +1,23 @@
+package com
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"modules=a&tail=10", "modules=a&tail=10"},
+		{"modules=a&secret=s3cr3t&tail=10", "modules=a&secret=REDACTED&tail=10"},
+		{"access_token=abc&api_key=def&Password=x", "access_token=REDACTED&api_key=REDACTED&Password=REDACTED"},
+		{"sig=abc&signature=def", "sig=REDACTED&signature=REDACTED"},
+		{"secret=&format=json", "secret=&format=json"},
+		{"se%63ret=abc", "se%63ret=REDACTED"},
+		{"flag&token", "flag&token"},
+	}
+	for _, tt := range tests {
+		if got := RedactQuery(tt.raw); got != tt.want {
+			t.Errorf("RedactQuery(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
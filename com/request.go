@@ -30,6 +30,7 @@ type Request interface {
 
 /*ConnexionRequest - server connexion request */
 type ConnexionRequest struct {
+	CommandSpecs   []CommandSpec
 	CustomCommands []string
 	ModHash        string
 	Name           string
@@ -127,14 +128,19 @@ func (cr *ConnexionReponseRequest) GetType() string {
 	return cr.Type
 }
 
-/*CommandRequest - CommandRequest*/
+/*CommandRequest - CommandRequest, sent to module of Hash or to modules of Target (names, comma separated, * for all) matching Selector (labels)*/
 type CommandRequest struct {
-	Command string
-	Content string
-	Hash    string
-	Name    string
-	Secret  string
-	Type    string
+	Args        Args `json:",omitempty"`
+	Async       bool `json:",omitempty"`
+	Command     string
+	Content     string
+	Hash        string
+	Name        string
+	Secret      string
+	Selector    string
+	Target      string
+	Traceparent string
+	Type        string
 }
 
 //Decode - Decode JSON to CommandRequest
@@ -177,3 +183,8 @@ func (cr *CommandRequest) GetSecret() string {
 func (cr *CommandRequest) GetType() string {
 	return cr.Type
 }
+
+/*GetTraceparent - CommandRequest trace context, parent of command spans*/
+func (cr *CommandRequest) GetTraceparent() string {
+	return cr.Traceparent
+}
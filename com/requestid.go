@@ -0,0 +1,22 @@
+package com
+
+//RequestIDHeader - Header carrying request id between clients, go-woxy and modules
+const RequestIDHeader = "X-Request-ID"
+
+//NewRequestID - Generate a request id
+func NewRequestID() string {
+	return randomID(16)
+}
+
+//ValidRequestID - Check request id received from a client can be kept : 1 to 128 letters, digits, '-', '_' or '.'
+func ValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
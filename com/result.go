@@ -0,0 +1,116 @@
+package com
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+)
+
+//Command result statuses
+const (
+	StatusError   = "error"
+	StatusSuccess = "success"
+)
+
+//Command result codes
+const (
+	CodeAccepted     = "ACCEPTED"
+	CodeBadRequest   = "BAD_REQUEST"
+	CodeCanceled     = "CANCELED"
+	CodeConflict     = "CONFLICT"
+	CodeInternal     = "INTERNAL"
+	CodeNotFound     = "NOT_FOUND"
+	CodeOK           = "OK"
+	CodePartial      = "PARTIAL"
+	CodeUnauthorized = "UNAUTHORIZED"
+	CodeUnavailable  = "UNAVAILABLE"
+)
+
+/*CommandError - Command error with result code*/
+type CommandError struct {
+	Code    string
+	Message string
+}
+
+/*CommandResult - Result of a command run by go-woxy or a module*/
+type CommandResult struct {
+	Code     string
+	Command  string
+	Data     json.RawMessage `json:",omitempty"`
+	Duration time.Duration
+	Message  string          `json:",omitempty"`
+	Module   string          `json:",omitempty"`
+	Results  []CommandResult `json:",omitempty"`
+	Status   string
+}
+
+//NewCommandError - Create command error with result code
+func NewCommandError(code string, message string) error {
+	return &CommandError{Code: code, Message: message}
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+//NewCommandResult - Create result of command output, JSON output is set as Data and text as Message
+//err sets error status with code of CommandError, INTERNAL otherwise
+func NewCommandResult(command string, out string, err error, d time.Duration) CommandResult {
+	r := CommandResult{Code: CodeOK, Command: command, Duration: d, Status: StatusSuccess}
+	if err != nil {
+		r.Code, r.Message, r.Status = CodeInternal, err.Error(), StatusError
+		var ce *CommandError
+		if errors.As(err, &ce) {
+			r.Code = ce.Code
+		}
+		return r
+	}
+
+	if b := bytes.TrimSpace([]byte(out)); len(b) > 0 && (b[0] == '{' || b[0] == '[') && json.Valid(b) {
+		r.Data = b
+	} else {
+		r.Message = out
+	}
+	return r
+}
+
+//ParseCommandResult - Decode CommandResult, ok is false when s is not a CommandResult
+func ParseCommandResult(s string) (CommandResult, bool) {
+	var r CommandResult
+	if err := json.Unmarshal([]byte(s), &r); err != nil || r.Status == "" || r.Code == "" {
+		return CommandResult{}, false
+	}
+	return r, true
+}
+
+//Success - Check command succeeded
+func (r CommandResult) Success() bool {
+	return r.Status == StatusSuccess
+}
+
+//Err - Get command error, nil on success
+func (r CommandResult) Err() error {
+	if r.Success() {
+		return nil
+	}
+	return &CommandError{Code: r.Code, Message: r.Message}
+}
+
+//Text - Get Data as text when set, Message otherwise
+func (r CommandResult) Text() string {
+	if len(r.Data) > 0 {
+		return string(r.Data)
+	}
+	return r.Message
+}
+
+//Encode - Encode CommandResult to JSON
+func (r CommandResult) Encode() []byte {
+	b, err := json.Marshal(r)
+	if err != nil {
+		log.Println("error:", err)
+	}
+	return b
+}
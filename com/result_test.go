@@ -0,0 +1,76 @@
+package com
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewCommandResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		err     error
+		code    string
+		status  string
+		data    string
+		message string
+	}{
+		{"text", "Success", nil, CodeOK, StatusSuccess, "", "Success"},
+		{"json object", ` {"a":1} `, nil, CodeOK, StatusSuccess, `{"a":1}`, ""},
+		{"json array", `[1,2]`, nil, CodeOK, StatusSuccess, `[1,2]`, ""},
+		{"invalid json", `{"a":`, nil, CodeOK, StatusSuccess, "", `{"a":`},
+		{"empty", "", nil, CodeOK, StatusSuccess, "", ""},
+		{"plain error", "ignored", errors.New("boom"), CodeInternal, StatusError, "", "boom"},
+		{"command error", "", NewCommandError(CodeNotFound, "Module m not found"), CodeNotFound, StatusError, "", "Module m not found"},
+		{"wrapped command error", "", fmt.Errorf("run : %w", NewCommandError(CodeConflict, "busy")), CodeConflict, StatusError, "", "run : busy"},
+	}
+	for _, tt := range tests {
+		r := NewCommandResult("Cmd", tt.out, tt.err, time.Second)
+		if r.Code != tt.code || r.Status != tt.status || string(r.Data) != tt.data || r.Message != tt.message || r.Command != "Cmd" || r.Duration != time.Second {
+			t.Errorf("%s : NewCommandResult = %+v", tt.name, r)
+		}
+		if r.Success() != (tt.err == nil) {
+			t.Errorf("%s : Success = %v", tt.name, r.Success())
+		}
+		if err := r.Err(); (err == nil) != (tt.err == nil) {
+			t.Errorf("%s : Err = %v", tt.name, err)
+		} else if ce, ok := err.(*CommandError); err != nil && (!ok || ce.Code != tt.code) {
+			t.Errorf("%s : Err = %v, want code %s", tt.name, err, tt.code)
+		}
+	}
+}
+
+func TestParseCommandResult(t *testing.T) {
+	nested := NewCommandResult("Restart", "", nil, 0)
+	nested.Results = []CommandResult{NewCommandResult("Restart", `{"pid":1}`, nil, 0), NewCommandResult("Restart", "", NewCommandError(CodeUnavailable, "down"), 0)}
+
+	tests := []struct {
+		name string
+		in   string
+		ok   bool
+		text string
+	}{
+		{"encoded result", string(NewCommandResult("Log", "line", nil, 0).Encode()), true, "line"},
+		{"data result", string(NewCommandResult("Perf", `{"cpu":1}`, nil, 0).Encode()), true, `{"cpu":1}`},
+		{"nested results", string(nested.Encode()), true, ""},
+		{"legacy text", "SHUTTING DOWN m", false, ""},
+		{"other json", `{"Status":"up"}`, false, ""},
+		{"empty", "", false, ""},
+	}
+	for _, tt := range tests {
+		r, ok := ParseCommandResult(tt.in)
+		if ok != tt.ok || r.Text() != tt.text {
+			t.Errorf("%s : ParseCommandResult = %+v, %v", tt.name, r, ok)
+		}
+	}
+
+	//DATA STAYS RAW JSON ONCE ENCODED, NOT A STRING
+	var m map[string]interface{}
+	json.Unmarshal(nested.Encode(), &m)
+	if d, ok := m["Results"].([]interface{})[0].(map[string]interface{})["Data"].(map[string]interface{}); !ok || d["pid"] != float64(1) {
+		t.Errorf("nested Data encoded as %v", m["Results"])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 //SendRequest - send request to server
@@ -27,11 +28,33 @@ func SendRequest(s Server, r Request, loging bool) (string, error) {
 
 	var url string = s.Protocol + "://" + s.IP + ":" + s.Port + customPath
 
+	//CONTINUE TRACE OF COMMAND IF ANY
+	parent := ""
+	if t, ok := r.(interface{ GetTraceparent() string }); ok {
+		parent = t.GetTraceparent()
+	}
+	span := StartSpan("send "+r.GetType(), SpanClient, parent)
+	span.Set("http.url", url)
+	if cr, ok := r.(*CommandRequest); ok {
+		span.Set("woxy.command", cr.Command)
+		span.Set("woxy.module", cr.Name)
+	}
+
 	//SEND REQUEST
-	resp, err := http.Post(url, "text/json", bytes.NewBuffer(r.Encode()))
+	var resp *http.Response
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(r.Encode()))
+	if err == nil {
+		req.Header.Set("Content-Type", "text/json")
+		req.Header.Set(TraceHeader, span.Context().Traceparent())
+		resp, err = http.DefaultClient.Do(req)
+	}
 	if err != nil {
 		log.Println(err)
 	}
+	if resp != nil {
+		span.Set("http.status_code", strconv.Itoa(resp.StatusCode))
+	}
+	span.Finish(err)
 	//defer resp.Body.Close()
 	if resp != nil && resp.Body != nil {
 		buf := new(bytes.Buffer)
@@ -47,14 +70,22 @@ func GetCustomRequestType(gRqt *http.Request) (map[string]string, []byte) {
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(gRqt.Body)
 
-	c := make(map[string]string)
+	var fields map[string]json.RawMessage
 
-	// unmarschal JSON
-	e := json.Unmarshal(buf.Bytes(), &c)
+	// unmarschal JSON, ONLY STRING FIELDS ARE KEPT
+	e := json.Unmarshal(buf.Bytes(), &fields)
 
 	if e != nil {
 		return map[string]string{"error": "error"}, nil
 	}
 
+	c := make(map[string]string)
+	for k, v := range fields {
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			c[k] = s
+		}
+	}
+
 	return c, buf.Bytes()
 }
@@ -0,0 +1,334 @@
+package com
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//TraceHeader - W3C Trace Context header
+const TraceHeader = "traceparent"
+
+/*SpanContext - Trace and span ids propagated between go-woxy and modules*/
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+/*Span - Timed operation of a trace*/
+type Span struct {
+	Attributes map[string]string
+	End        time.Time
+	Error      string
+	Kind       string
+	Name       string
+	ParentID   string
+	Sampled    bool `json:"-"`
+	Service    string
+	SpanID     string
+	Start      time.Time
+	TraceID    string
+}
+
+/*SpanExporter - Send finished spans to a tracing backend*/
+type SpanExporter interface {
+	Export(spans []Span) error
+}
+
+//Span kinds
+const (
+	SpanClient   = "client"
+	SpanInternal = "internal"
+	SpanServer   = "server"
+)
+
+var tracer struct {
+	mux      sync.Mutex
+	service  string
+	exporter SpanExporter
+	spans    chan Span
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+//ParseTraceparent - Parse traceparent header "00-<trace id>-<span id>-<flags>"
+func ParseTraceparent(h string) (SpanContext, bool) {
+	p := strings.Split(strings.TrimSpace(h), "-")
+	if len(p) < 4 || len(p[0]) != 2 || p[0] == "ff" || len(p[1]) != 32 || len(p[2]) != 16 || len(p[3]) != 2 {
+		return SpanContext{}, false
+	}
+	if _, err := hex.DecodeString(p[1] + p[2] + p[3]); err != nil || strings.Trim(p[1], "0") == "" || strings.Trim(p[2], "0") == "" {
+		return SpanContext{}, false
+	}
+	flags, _ := strconv.ParseUint(p[3], 16, 8)
+	return SpanContext{TraceID: p[1], SpanID: p[2], Sampled: flags&1 == 1}, true
+}
+
+//Traceparent - Format SpanContext as traceparent header
+func (sc SpanContext) Traceparent() string {
+	if sc.TraceID == "" {
+		return ""
+	}
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID + "-" + sc.SpanID + "-" + flags
+}
+
+//StartSpan - Start span as child of traceparent, or as root of a new sampled trace when traceparent is empty or invalid
+//Child span keeps sampled flag of its parent
+func StartSpan(name string, kind string, traceparent string) *Span {
+	s := Span{Attributes: map[string]string{}, Kind: kind, Name: name, SpanID: randomID(8), Start: time.Now()}
+	if parent, ok := ParseTraceparent(traceparent); ok {
+		s.TraceID, s.ParentID, s.Sampled = parent.TraceID, parent.SpanID, parent.Sampled
+	} else {
+		s.TraceID, s.Sampled = randomID(16), true
+	}
+	return &s
+}
+
+//Context - Get SpanContext to propagate to children of span
+func (s *Span) Context() SpanContext {
+	return SpanContext{TraceID: s.TraceID, SpanID: s.SpanID, Sampled: s.Sampled}
+}
+
+//Set - Set span attribute
+func (s *Span) Set(key string, value string) {
+	s.Attributes[key] = value
+}
+
+//Finish - End span and hand it to exporter, err marks span as failed, span of trace not sampled is not exported
+func (s *Span) Finish(err error) {
+	s.End = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if !s.Sampled {
+		return
+	}
+
+	tracer.mux.Lock()
+	spans := tracer.spans
+	s.Service = tracer.service
+	tracer.mux.Unlock()
+	if spans == nil {
+		return
+	}
+
+	//NEVER BLOCK TRAFFIC ON A SLOW EXPORTER, DROP SPAN INSTEAD
+	select {
+	case spans <- *s:
+	default:
+	}
+}
+
+//InitTracing - Export spans of service with exporter "otlp" (endpoint is collector URL) or "file" (endpoint is JSON-lines file path)
+func InitTracing(service string, exporter string, endpoint string) error {
+	var e SpanExporter
+	switch exporter {
+	case "":
+		return nil
+	case "otlp":
+		if endpoint == "" {
+			endpoint = "http://127.0.0.1:4318/v1/traces"
+		}
+		e = &OTLPExporter{URL: endpoint}
+	case "file":
+		f, err := NewFileExporter(endpoint)
+		if err != nil {
+			return err
+		}
+		e = f
+	default:
+		return errors.New("unknown trace exporter " + exporter)
+	}
+
+	tracer.mux.Lock()
+	defer tracer.mux.Unlock()
+	tracer.service = service
+	tracer.exporter = e
+	if tracer.spans == nil {
+		tracer.spans = make(chan Span, 1024)
+		go exportSpans(tracer.spans)
+	}
+	return nil
+}
+
+//exportSpans - Export spans by batches of 100 or every second
+func exportSpans(spans chan Span) {
+	var batch []Span
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		tracer.mux.Lock()
+		e := tracer.exporter
+		tracer.mux.Unlock()
+		if err := e.Export(batch); err != nil {
+			log.Println("Error exporting spans :", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s := <-spans:
+			batch = append(batch, s)
+			if len(batch) >= 100 {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+/*FileExporter - Write spans as JSON lines*/
+type FileExporter struct {
+	mux  sync.Mutex
+	file *os.File
+}
+
+//NewFileExporter - Open JSON-lines file, spans are appended
+func NewFileExporter(path string) (*FileExporter, error) {
+	if path == "" {
+		return nil, errors.New("file trace exporter without path")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileExporter{file: f}, nil
+}
+
+//Export - Append spans to file, one JSON object per line and per write
+func (fe *FileExporter) Export(spans []Span) error {
+	fe.mux.Lock()
+	defer fe.mux.Unlock()
+
+	for i := range spans {
+		b, err := json.Marshal(spans[i])
+		if err != nil {
+			return err
+		}
+		if _, err := fe.file.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*OTLPExporter - Send spans to an OpenTelemetry collector with OTLP/HTTP JSON*/
+type OTLPExporter struct {
+	URL string
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Kind              int             `json:"kind"`
+	Name              string          `json:"name"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	SpanID            string          `json:"spanId"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	Status            otlpStatus      `json:"status"`
+	TraceID           string          `json:"traceId"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+//otlpKinds - OTLP SpanKind values
+var otlpKinds = map[string]int{SpanInternal: 1, SpanServer: 2, SpanClient: 3}
+
+//Export - POST spans grouped by service to collector
+func (oe *OTLPExporter) Export(spans []Span) error {
+	var res []otlpResourceSpans
+	byService := map[string]int{}
+	for _, s := range spans {
+		i, ok := byService[s.Service]
+		if !ok {
+			var rs otlpResourceSpans
+			rs.Resource.Attributes = []otlpAttribute{{Key: "service.name", Value: otlpValue{s.Service}}}
+			rs.ScopeSpans = []otlpScopeSpans{{}}
+			rs.ScopeSpans[0].Scope.Name = "go-woxy"
+			res = append(res, rs)
+			i = len(res) - 1
+			byService[s.Service] = i
+		}
+
+		o := otlpSpan{
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Kind:              otlpKinds[s.Kind],
+			Name:              s.Name,
+			ParentSpanID:      s.ParentID,
+			SpanID:            s.SpanID,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			TraceID:           s.TraceID,
+		}
+		for k, v := range s.Attributes {
+			o.Attributes = append(o.Attributes, otlpAttribute{Key: k, Value: otlpValue{v}})
+		}
+		if s.Error != "" {
+			o.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		res[i].ScopeSpans[0].Spans = append(res[i].ScopeSpans[0].Spans, o)
+	}
+
+	b, err := json.Marshal(map[string][]otlpResourceSpans{"resourceSpans": res})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(oe.URL, "application/json", bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.New("collector answered " + resp.Status)
+	}
+	return nil
+}
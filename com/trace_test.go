@@ -0,0 +1,116 @@
+package com
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		ok     bool
+		want   SpanContext
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03 ", true, SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", true, SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}},
+		{"", false, SpanContext{}},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, SpanContext{}},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, SpanContext{}},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, SpanContext{}},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", false, SpanContext{}},
+		{"00-zbf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, SpanContext{}},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, SpanContext{}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTraceparent(tt.header)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseTraceparent(%q) = %+v, %v, want %+v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+//collectorStub - OTLP collector recording received span ids
+type collectorStub struct {
+	mux   sync.Mutex
+	spans map[string]otlpSpan
+}
+
+func (c *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := ioutil.ReadAll(r.Body)
+	var req map[string][]otlpResourceSpans
+	if err := json.Unmarshal(b, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, rs := range req["resourceSpans"] {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				c.spans[s.SpanID] = s
+			}
+		}
+	}
+}
+
+func (c *collectorStub) get(id string) (otlpSpan, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	s, ok := c.spans[id]
+	return s, ok
+}
+
+func TestSpanSampling(t *testing.T) {
+	c := &collectorStub{spans: map[string]otlpSpan{}}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	if err := InitTracing("test", "otlp", srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		traceparent string
+		sampled     bool
+		parent      string
+	}{
+		{"sampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, "00f067aa0ba902b7"},
+		{"unsampled parent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", false, "00f067aa0ba902b7"},
+		{"root", "", true, ""},
+		{"invalid parent", "00-bad", true, ""},
+	}
+
+	spans := map[string]*Span{}
+	for _, tt := range tests {
+		s := StartSpan(tt.name, SpanServer, tt.traceparent)
+		child := StartSpan(tt.name+" child", SpanClient, s.Context().Traceparent())
+		if s.Context().Sampled != tt.sampled || child.Context().Sampled != tt.sampled {
+			t.Errorf("%s : sampled = %v, child %v, want %v", tt.name, s.Context().Sampled, child.Context().Sampled, tt.sampled)
+		}
+		if child.TraceID != s.TraceID || child.ParentID != s.SpanID {
+			t.Errorf("%s : child not in trace of parent span", tt.name)
+		}
+		child.Finish(nil)
+		s.Finish(nil)
+		spans[tt.name] = s
+	}
+
+	//EXPORTER FLUSHES EVERY SECOND
+	time.Sleep(1500 * time.Millisecond)
+	for _, tt := range tests {
+		s := spans[tt.name]
+		got, ok := c.get(s.SpanID)
+		if ok != tt.sampled {
+			t.Errorf("%s : exported = %v, want %v", tt.name, ok, tt.sampled)
+		} else if ok && (got.ParentSpanID != tt.parent || got.TraceID != s.TraceID) {
+			t.Errorf("%s : exported parent %q trace %q, want %q %q", tt.name, got.ParentSpanID, got.TraceID, tt.parent, s.TraceID)
+		}
+	}
+}
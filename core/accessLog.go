@@ -0,0 +1,224 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	zLog "github.com/rs/zerolog/log"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//Access log formats
+const (
+	AccessLogCombined = "combined"
+	AccessLogCommon   = "common"
+	AccessLogConsole  = "console"
+	AccessLogJSON     = "json"
+)
+
+//gin context keys set while serving a request
+const (
+	requestIDKey       = "request_id"
+	upstreamKey        = "upstream"
+	upstreamLatencyKey = "upstream_latency"
+)
+
+//AccessLogConfig - Access log format and output
+type AccessLogConfig struct {
+	FORMAT    string
+	MAX_AGE   time.Duration
+	MAX_FILES int
+	MAX_SIZE  int64
+	PATH      string
+}
+
+//BindingAccessLog - Access log of requests served by a module binding
+type BindingAccessLog struct {
+	DISABLED bool
+	SAMPLE   float64
+}
+
+//accessEntry - Served request fields
+type accessEntry struct {
+	Bytes           int     `json:"bytes"`
+	LatencyMs       float64 `json:"latency_ms"`
+	Method          string  `json:"method"`
+	Path            string  `json:"path"`
+	Proto           string  `json:"proto"`
+	Referer         string  `json:"referer,omitempty"`
+	RemoteAddr      string  `json:"remote_addr"`
+	RequestID       string  `json:"request_id"`
+	Status          int     `json:"status"`
+	Time            string  `json:"time"`
+	Upstream        string  `json:"upstream,omitempty"`
+	UpstreamLatency float64 `json:"upstream_latency_ms,omitempty"`
+	User            string  `json:"user,omitempty"`
+	UserAgent       string  `json:"user_agent,omitempty"`
+
+	time time.Time
+}
+
+//requestID - Middleware keeping or generating X-Request-ID, forwarded to modules and echoed in response
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(com.RequestIDHeader)
+		if !com.ValidRequestID(id) {
+			id = com.NewRequestID()
+		}
+		c.Request.Header.Set(com.RequestIDHeader, id)
+		c.Header(com.RequestIDHeader, id)
+		c.Set(requestIDKey, id)
+		c.Next()
+	}
+}
+
+//sampled - Check if request of binding is logged
+func (b BindingAccessLog) sampled() bool {
+	if b.DISABLED {
+		return false
+	}
+	return b.SAMPLE <= 0 || b.SAMPLE >= 1 || rand.Float64() < b.SAMPLE
+}
+
+//writer - Get access log output, stdout when no file is set
+func (a AccessLogConfig) writer() io.Writer {
+	if a.PATH == "" {
+		return os.Stdout
+	}
+	return newRotatingFile(a.PATH, a.MAX_SIZE*1024*1024, a.MAX_AGE, a.MAX_FILES)
+}
+
+//accessLogger - Middleware logging served requests in configured format
+func accessLogger() gin.HandlerFunc {
+	//ROUTER IS CREATED BEFORE CONFIG IS LOADED
+	var once sync.Once
+	var out io.Writer
+	var console zerolog.Logger
+
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		path := ctx.Request.URL.Path
+		if raw := ctx.Request.URL.RawQuery; raw != "" {
+			path = path + "?" + com.RedactQuery(raw)
+		}
+
+		ctx.Next()
+
+		cfg := GetManager().GetConfig().ACCESS_LOG
+		once.Do(func() {
+			out = cfg.writer()
+			console = zLog.Logger
+			if cfg.PATH != "" {
+				console = zerolog.New(zerolog.ConsoleWriter{Out: out, NoColor: true, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+			}
+		})
+
+		upstream := ctx.GetString(upstreamKey)
+		if upstream != "" && !GetManager().GetModule(upstream).BINDING.ACCESS_LOG.sampled() {
+			return
+		}
+
+		e := accessEntry{
+			Bytes:      ctx.Writer.Size(),
+			LatencyMs:  float64(time.Since(start)) / float64(time.Millisecond),
+			Method:     ctx.Request.Method,
+			Path:       path,
+			Proto:      ctx.Request.Proto,
+			Referer:    ctx.Request.Referer(),
+			RemoteAddr: ctx.ClientIP(),
+			RequestID:  ctx.GetString(requestIDKey),
+			Status:     ctx.Writer.Status(),
+			Upstream:   upstream,
+			User:       ctx.GetString("user"),
+			UserAgent:  ctx.Request.UserAgent(),
+			time:       start,
+		}
+		if e.Bytes < 0 {
+			e.Bytes = 0
+		}
+		if d, ok := ctx.Get(upstreamLatencyKey); ok {
+			e.UpstreamLatency = float64(d.(time.Duration)) / float64(time.Millisecond)
+		}
+
+		var err error
+		switch cfg.FORMAT {
+		case AccessLogJSON:
+			e.Time = start.Format(time.RFC3339Nano)
+			var b []byte
+			if b, err = json.Marshal(e); err == nil {
+				_, err = out.Write(append(b, '\n'))
+			}
+		case AccessLogCommon:
+			_, err = io.WriteString(out, e.common()+"\n")
+		case AccessLogCombined:
+			_, err = io.WriteString(out, e.common()+" "+strconv.Quote(dash(e.Referer))+" "+strconv.Quote(dash(e.UserAgent))+"\n")
+		default:
+			e.console(ctx, console)
+		}
+		if err != nil {
+			log.Println("GO-WOXY Core - Error writing access log :", err)
+		}
+	}
+}
+
+//common - Format entry in Common Log Format
+func (e accessEntry) common() string {
+	host := e.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return dash(host) + " - " + dash(e.User) + " [" + e.time.Format("02/Jan/2006:15:04:05 -0700") + "] " +
+		strconv.Quote(e.Method+" "+e.Path+" "+e.Proto) + " " + strconv.Itoa(e.Status) + " " + strconv.Itoa(e.Bytes)
+}
+
+//dash - Common Log Format empty value
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+//console - Log entry with zerolog console logger
+func (e accessEntry) console(ctx *gin.Context, logger zerolog.Logger) {
+	msg := "Request"
+	if len(ctx.Errors) > 0 {
+		msg = ctx.Errors.String()
+	}
+
+	lc := logger.With().
+		Int("status", e.Status).
+		Str("method", e.Method).
+		Str("path", e.Path).
+		Str("ip", e.RemoteAddr).
+		Dur("latency", time.Duration(e.LatencyMs*float64(time.Millisecond))).
+		Str("user-agent", e.UserAgent).
+		Str(requestIDKey, e.RequestID)
+	if e.Upstream != "" {
+		lc = lc.Str("upstream", e.Upstream).Float64("upstream_latency_ms", e.UpstreamLatency)
+	}
+	if e.User != "" {
+		lc = lc.Str("user", e.User)
+	}
+	l := lc.Logger()
+
+	switch {
+	case e.Status >= http.StatusInternalServerError:
+		l.Error().Msg(msg)
+	case e.Status >= http.StatusBadRequest:
+		l.Warn().Msg(msg)
+	default:
+		l.Info().Msg(msg)
+	}
+}
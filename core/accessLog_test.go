@@ -0,0 +1,151 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Wariie/go-woxy/com"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDLogged(t *testing.T) {
+	//CLOSED PORT SO PROXY FAILS
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	GetManager().SetState(&Config{
+		LOG:     LogConfig{BUFFER: 100},
+		SECRET:  "s",
+		WEBHOOK: WebhookConfig{PATH: "/hook", SECRET: "h"},
+		MODULES: map[string]ModuleConfig{"m": {NAME: "m", TYPES: "web", STATE: Online, BINDING: ServerConfig{ADDRESS: "127.0.0.1", PORT: port, PROTOCOL: "http"}}},
+	})
+
+	router := gin.New()
+	router.Use(requestID())
+	router.POST("/cmd", command)
+	router.POST("/hook", webhook)
+	router.GET("/m", ReverseProxy("m", Route{FROM: "/m", TO: "/"}))
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"command", http.MethodPost, "/cmd", "GO-WOXY Core - Command request_id=rid-command"},
+		{"webhook", http.MethodPost, "/hook", "invalid signature request_id=rid-webhook"},
+		{"proxy", http.MethodGet, "/m", "Error proxying to mod m"},
+	}
+	for _, tt := range tests {
+		buf.Reset()
+		id := "rid-" + tt.name
+		r, _ := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader("{}"))
+		r.Header.Set(com.RequestIDHeader, id)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		out := buf.String()
+		if !strings.Contains(out, tt.want) {
+			t.Errorf("%s : log %q does not contain %q", tt.name, out, tt.want)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if !strings.HasSuffix(line, "request_id="+id) {
+				t.Errorf("%s : log line %q does not end with request id", tt.name, line)
+			}
+		}
+	}
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upstream"))
+	}))
+	defer up.Close()
+	_, port, _ := net.SplitHostPort(up.Listener.Addr().String())
+	binding := func(a BindingAccessLog) ServerConfig {
+		return ServerConfig{ADDRESS: "127.0.0.1", PORT: port, PROTOCOL: "http", ACCESS_LOG: a}
+	}
+
+	clf := `127\.0\.0\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /core\?secret=REDACTED&a=1 HTTP/1\.1" 201 5`
+	tests := []struct {
+		format string
+		path   string
+		want   string
+	}{
+		{AccessLogCommon, "/core?secret=s&a=1", "^" + clf + "$"},
+		{AccessLogCombined, "/core?secret=s&a=1", "^" + clf + ` "http://ref/" "woxy-test"$`},
+		{AccessLogJSON, "/core?secret=s&a=1", `^\{"bytes":5,.*"path":"/core\?secret=REDACTED\\u0026a=1",.*"request_id":"rid","status":201,.*"user":"alice","user_agent":"woxy-test"\}$`},
+		{AccessLogCommon, "/on", `"GET /on HTTP/1\.1" 200 8$`},
+		{AccessLogCommon, "/sampled", `"GET /sampled HTTP/1\.1" 200 8$`},
+		{AccessLogCommon, "/off", `^$`},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "woxy-access")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		GetManager().SetState(&Config{
+			LOG:        LogConfig{BUFFER: 100},
+			ACCESS_LOG: AccessLogConfig{FORMAT: tt.format, PATH: filepath.Join(dir, "access.log")},
+			MODULES: map[string]ModuleConfig{
+				"on":      {NAME: "on", TYPES: "web", STATE: Online, BINDING: binding(BindingAccessLog{})},
+				"sampled": {NAME: "sampled", TYPES: "web", STATE: Online, BINDING: binding(BindingAccessLog{SAMPLE: 1})},
+				"off":     {NAME: "off", TYPES: "web", STATE: Online, BINDING: binding(BindingAccessLog{DISABLED: true})},
+			},
+		})
+
+		router := gin.New()
+		router.Use(requestID(), accessLogger())
+		router.GET("/core", func(c *gin.Context) {
+			c.Set("user", "alice")
+			c.String(http.StatusCreated, "hello")
+		})
+		for _, m := range []string{"on", "sampled", "off"} {
+			router.GET("/"+m, ReverseProxy(m, Route{FROM: "/" + m, TO: "/"}))
+		}
+		srv := httptest.NewServer(router)
+
+		r, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		r.Header.Set(com.RequestIDHeader, "rid")
+		r.Header.Set("Referer", "http://ref/")
+		r.Header.Set("User-Agent", "woxy-test")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		srv.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			t.Errorf("%s %s : status %d", tt.format, tt.path, resp.StatusCode)
+		}
+
+		b, _ := ioutil.ReadFile(filepath.Join(dir, "access.log"))
+		if got := strings.TrimSuffix(string(b), "\n"); !regexp.MustCompile(tt.want).MatchString(got) {
+			t.Errorf("%s %s : access log %q does not match %s", tt.format, tt.path, got, tt.want)
+		}
+	}
+}
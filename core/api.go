@@ -0,0 +1,637 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Wariie/go-woxy/com"
+	"github.com/gin-gonic/gin"
+)
+
+//apiPrefix - Versioned REST API path
+const apiPrefix = "/api/v1"
+
+//Pagination limits of list endpoints
+const (
+	apiDefaultLimit = 50
+	apiMaxLimit     = 500
+)
+
+//apiParam - Path or query parameter of an API route
+type apiParam struct {
+	name        string
+	in          string
+	typ         string
+	description string
+}
+
+//apiResponse - Response of an API route, body is a sample value of JSON answer used for OpenAPI schema
+type apiResponse struct {
+	description string
+	body        interface{}
+}
+
+//apiOneOf - Response body that may be any of its values
+type apiOneOf []interface{}
+
+//apiRoute - REST API route, source of both router and OpenAPI document
+type apiRoute struct {
+	method    string
+	path      string
+	summary   string
+	params    []apiParam
+	responses map[int]apiResponse
+	public    bool
+	handler   gin.HandlerFunc
+}
+
+//apiError - JSON error body
+type apiError struct {
+	ERROR  string
+	STATUS int
+}
+
+//apiPage - Page of a list endpoint
+type apiPage struct {
+	ITEMS  interface{}
+	LIMIT  int
+	OFFSET int
+	TOTAL  int
+}
+
+//apiRouteSummary - Route served by a module
+type apiRouteSummary struct {
+	FROM   string
+	MODULE string
+	TO     string
+}
+
+var (
+	nameParam   = apiParam{"name", "path", "string", "Module name"}
+	limitParam  = apiParam{"limit", "query", "integer", "Page size (default : 50, max : 500)"}
+	offsetParam = apiParam{"offset", "query", "integer", "Index of first item"}
+
+	commandParam = apiParam{"command", "path", "string", "Command name, core or module custom command"}
+	jobParam     = apiParam{"id", "path", "string", "Job id"}
+	preferParam  = apiParam{"Prefer", "header", "string", "respond-async to run command as a job, answered with 202 and the job"}
+)
+
+//apiRoutes - REST API routes
+var apiRoutes []apiRoute
+
+func init() {
+	apiRoutes = []apiRoute{
+		{"GET", "/modules", "List modules", []apiParam{{"selector", "query", "string", "Label selector (example : env=prod,tier!=batch)"}, limitParam, offsetParam},
+			map[int]apiResponse{200: {"Page of modules", apiPage{ITEMS: []moduleSummary{}}}, 400: {"Invalid selector or page", apiError{}}}, false, apiModules},
+		{"GET", "/modules/:name", "Get module", []apiParam{nameParam},
+			map[int]apiResponse{200: {"Module", moduleSummary{}}, 404: {"Module not found", apiError{}}}, false, apiModule},
+		{"GET", "/modules/:name/health", "Get module probes status", []apiParam{nameParam},
+			map[int]apiResponse{200: {"Health status", HealthStatus{}}, 404: {"Module not found", apiError{}}}, false, apiHealth},
+		{"GET", "/modules/:name/logs", "Query module log", []apiParam{nameParam,
+			{"tail", "query", "integer", "Last lines only"},
+			{"since", "query", "string", "Lines since duration or RFC3339 time"},
+			{"grep", "query", "string", "Lines matching regular expression"},
+			{"level", "query", "string", "Lines at or above level"},
+			{"request_id", "query", "string", "Lines of a request"},
+			limitParam, offsetParam},
+			map[int]apiResponse{200: {"Page of log lines", apiPage{ITEMS: []LogLine{}}}, 400: {"Invalid query", apiError{}}, 404: {"Module not found", apiError{}}}, false, apiLogs},
+		{"GET", "/modules/:name/performance", "Get module performance samples", []apiParam{nameParam,
+			{"from", "query", "string", "Start as duration or RFC3339 time (default : 1h)"},
+			{"to", "query", "string", "End as duration or RFC3339 time (default : now)"}},
+			map[int]apiResponse{200: {"Performance series", PerfSeries{}}, 400: {"Invalid range", apiError{}}, 404: {"Module not found", apiError{}}}, false, apiPerformance},
+		{"POST", "/modules/:name/start", "Start module", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Job started", Job{}}, 200: {"Module started", moduleSummary{}}, 404: {"Module not found", apiError{}}, 409: {"Module already online", apiError{}}}, false, apiAction("Start")},
+		{"POST", "/modules/:name/stop", "Stop module", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Job started", Job{}}, 200: {"Module stopped", moduleSummary{}}, 404: {"Module not found", apiError{}}}, false, apiAction("Stop")},
+		{"POST", "/modules/:name/restart", "Restart module", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Job started", Job{}}, 200: {"Module restarted", moduleSummary{}}, 404: {"Module not found", apiError{}}}, false, apiAction("Restart")},
+		{"POST", "/modules/:name/kill", "Kill module process group", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Job started", Job{}}, 200: {"Module killed", moduleSummary{}}, 404: {"Module not found", apiError{}}}, false, apiAction("Kill")},
+		{"POST", "/modules/:name/rollback", "Restart module on previous commit", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Job started", Job{}}, 200: {"Module rolled back", moduleSummary{}}, 404: {"Module not found", apiError{}}, 409: {"No previous commit", apiError{}}}, false, apiAction("Rollback")},
+		{"POST", "/modules/:name/deploy", "Deploy module ref", []apiParam{nameParam, preferParam},
+			map[int]apiResponse{202: {"Deployment started, or job", apiOneOf{Deployment{}, Job{}}}, 404: {"Module not found", apiError{}}, 409: {"Module is not a git module", apiError{}}}, false, apiAction("Deploy")},
+		{"GET", "/modules/:name/commands", "List commands of module with their parameters", []apiParam{nameParam},
+			map[int]apiResponse{200: {"Command specs", []com.CommandSpec{}}, 404: {"Module not found", apiError{}}}, false, apiHelp},
+		{"GET", "/modules/:name/commands/:command", "Describe command parameters", []apiParam{nameParam, commandParam},
+			map[int]apiResponse{200: {"Command spec", com.CommandSpec{}}, 404: {"Module or command not found", apiError{}}}, false, apiHelp},
+		{"POST", "/modules/:name/commands/:command", "Run command on module, query parameters are command arguments and request body is command content", []apiParam{nameParam, commandParam, preferParam},
+			map[int]apiResponse{200: {"Command result", com.CommandResult{}}, 202: {"Job started, job is result data", com.CommandResult{}}, 400: {"Invalid command arguments or content", com.CommandResult{}},
+				404: {"Module or command not found", com.CommandResult{}}, 409: {"Command conflicts with module state", com.CommandResult{}}, 500: {"Command failed", com.CommandResult{}}, 502: {"Module unavailable", com.CommandResult{}}}, false, apiCommand},
+		{"GET", "/routes", "List module routes", []apiParam{limitParam, offsetParam},
+			map[int]apiResponse{200: {"Page of routes", apiPage{ITEMS: []apiRouteSummary{}}}, 400: {"Invalid page", apiError{}}}, false, apiModuleRoutes},
+		{"GET", "/jobs", "List jobs of asynchronous commands, newest first", []apiParam{limitParam, offsetParam},
+			map[int]apiResponse{200: {"Page of jobs", apiPage{ITEMS: []Job{}}}, 400: {"Invalid page", apiError{}}}, false, apiJobs},
+		{"GET", "/jobs/:id", "Get job progress and result", []apiParam{jobParam,
+			{"wait", "query", "string", "Duration to wait for job to finish, at most 1m (long polling)"}},
+			map[int]apiResponse{200: {"Job", Job{}}, 400: {"Invalid wait", apiError{}}, 404: {"Job not found", apiError{}}}, false, apiJob},
+		{"DELETE", "/jobs/:id", "Cancel job", []apiParam{jobParam},
+			map[int]apiResponse{202: {"Job canceling", Job{}}, 404: {"Job not found", apiError{}}, 409: {"Job already finished", apiError{}}}, false, apiCancelJob},
+		{"GET", "/deployments", "List deployments, newest first", []apiParam{limitParam, offsetParam},
+			map[int]apiResponse{200: {"Page of deployments", apiPage{ITEMS: []Deployment{}}}, 400: {"Invalid page", apiError{}}}, false, apiDeployments},
+		{"GET", "/openapi.json", "Get OpenAPI document", nil,
+			map[int]apiResponse{200: {"OpenAPI 3 document", map[string]interface{}{}}}, true, apiOpenAPI},
+	}
+}
+
+//serveAPI - Serve REST API, server secret is required as bearer token
+func serveAPI(router *gin.Engine) {
+	g := router.Group(apiPrefix)
+	for _, r := range apiRoutes {
+		if r.public {
+			g.Handle(r.method, r.path, r.handler)
+		} else {
+			g.Handle(r.method, r.path, apiAuth, r.handler)
+		}
+	}
+}
+
+//apiAuth - Check server secret of request
+func apiAuth(c *gin.Context) {
+	if !hashMatchSecretHash(requestSecret(c.Request)) {
+		c.Header("WWW-Authenticate", "Bearer")
+		apiFail(c, http.StatusUnauthorized, errors.New("Secret not matching with server"))
+	}
+}
+
+//apiFail - Abort request with JSON error
+func apiFail(c *gin.Context, status int, err error) {
+	c.AbortWithStatusJSON(status, apiError{ERROR: err.Error(), STATUS: status})
+}
+
+//apiPaginate - Get page bounds of n items from limit and offset query parameters
+func apiPaginate(c *gin.Context, n int) (apiPage, int, int, error) {
+	p := apiPage{LIMIT: apiDefaultLimit, TOTAL: n}
+	var err error
+	if l := c.Query("limit"); l != "" {
+		if p.LIMIT, err = strconv.Atoi(l); err != nil || p.LIMIT <= 0 || p.LIMIT > apiMaxLimit {
+			return p, 0, 0, errors.New("invalid limit : " + l)
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if p.OFFSET, err = strconv.Atoi(o); err != nil || p.OFFSET < 0 {
+			return p, 0, 0, errors.New("invalid offset : " + o)
+		}
+	}
+
+	start, end := p.OFFSET, p.OFFSET+p.LIMIT
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+	return p, start, end, nil
+}
+
+//apiModuleConfig - Get module of name parameter, failing with 404 when unknown
+func apiModuleConfig(c *gin.Context) (ModuleConfig, bool) {
+	name := c.Param("name")
+	mc, ok := GetManager().LookupModule(name)
+	if !ok {
+		apiFail(c, http.StatusNotFound, errors.New("module "+name+" not found"))
+	}
+	return mc, ok
+}
+
+func apiModules(c *gin.Context) {
+	mods, err := GetManager().GetConfig().selectModules("*", c.Query("selector"))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	p, start, end, err := apiPaginate(c, len(mods))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+
+	items := []moduleSummary{}
+	for i := range mods[start:end] {
+		items = append(items, mods[start+i].summary())
+	}
+	p.ITEMS = items
+	c.JSON(http.StatusOK, p)
+}
+
+func apiModule(c *gin.Context) {
+	if mc, ok := apiModuleConfig(c); ok {
+		c.JSON(http.StatusOK, mc.summary())
+	}
+}
+
+func apiHealth(c *gin.Context) {
+	if mc, ok := apiModuleConfig(c); ok {
+		c.JSON(http.StatusOK, GetManager().GetHealth(mc.NAME))
+	}
+}
+
+func apiLogs(c *gin.Context) {
+	mc, ok := apiModuleConfig(c)
+	if !ok {
+		return
+	}
+	q, err := parseLogQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+
+	lines := GetManager().GetLogStore(mc.NAME).Query(q)
+	p, start, end, err := apiPaginate(c, len(lines))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	p.ITEMS = append([]LogLine{}, lines[start:end]...)
+	c.JSON(http.StatusOK, p)
+}
+
+func apiPerformance(c *gin.Context) {
+	mc, ok := apiModuleConfig(c)
+	if !ok {
+		return
+	}
+	from, to, err := parsePerfQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, GetManager().GetPerfStore().Query(mc.NAME, from, to))
+}
+
+//apiStatus - HTTP status of command result code
+func apiStatus(code string) int {
+	switch code {
+	case com.CodeOK:
+		return http.StatusOK
+	case com.CodeAccepted:
+		return http.StatusAccepted
+	case com.CodePartial:
+		return http.StatusMultiStatus
+	case com.CodeBadRequest:
+		return http.StatusBadRequest
+	case com.CodeConflict, com.CodeCanceled:
+		return http.StatusConflict
+	case com.CodeNotFound:
+		return http.StatusNotFound
+	case com.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case com.CodeUnavailable:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+//apiAction - Run module command through command processor
+func apiAction(command string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mc, ok := apiModuleConfig(c)
+		if !ok {
+			return
+		}
+
+		cr := com.CommandRequest{Async: apiAsync(c), Command: command, Name: mc.NAME, Type: "Command"}
+		res := apiRun(&mc, cr, c.GetHeader(com.TraceHeader))
+		if !res.Success() {
+			apiFail(c, apiStatus(res.Code), res.Err())
+			return
+		}
+
+		if res.Code == com.CodeAccepted {
+			c.Data(http.StatusAccepted, "application/json; charset=utf-8", res.Data)
+			return
+		}
+
+		if command == "Deploy" {
+			var d Deployment
+			if err := json.Unmarshal(res.Data, &d); err != nil {
+				apiFail(c, http.StatusInternalServerError, err)
+				return
+			}
+			c.JSON(http.StatusAccepted, d)
+			return
+		}
+		m := GetManager().GetModule(mc.NAME)
+		c.JSON(http.StatusOK, m.summary())
+	}
+}
+
+func apiCommand(c *gin.Context) {
+	mc, ok := apiModuleConfig(c)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+
+	var args com.Args
+	for k := range c.Request.URL.Query() {
+		if args == nil {
+			args = com.Args{}
+		}
+		args[k] = c.Query(k)
+	}
+
+	cr := com.CommandRequest{Args: args, Async: apiAsync(c), Command: c.Param("command"), Content: strings.TrimSpace(string(body)), Name: mc.NAME, Type: "Command"}
+	res := apiRun(&mc, cr, c.GetHeader(com.TraceHeader))
+	c.JSON(apiStatus(res.Code), res)
+}
+
+//apiAsync - Check request asks command to run as a job (Prefer: respond-async)
+func apiAsync(c *gin.Context) bool {
+	for _, p := range strings.Split(c.GetHeader("Prefer"), ",") {
+		if strings.TrimSpace(p) == "respond-async" {
+			return true
+		}
+	}
+	return false
+}
+
+//apiRun - Run command request on module, in background when it is async
+func apiRun(mc *ModuleConfig, cr com.CommandRequest, traceparent string) com.CommandResult {
+	if cr.Async {
+		return startModuleJob(mc, cr, traceparent)
+	}
+	return runModuleRequest(context.Background(), mc, cr, traceparent)
+}
+
+func apiJobs(c *gin.Context) {
+	jobs := GetManager().GetJobs()
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].CREATED.After(jobs[j].CREATED)
+	})
+
+	p, start, end, err := apiPaginate(c, len(jobs))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	p.ITEMS = append([]Job{}, jobs[start:end]...)
+	c.JSON(http.StatusOK, p)
+}
+
+func apiJob(c *gin.Context) {
+	hub := GetManager().GetModule("hub")
+	args := com.Args{"id": c.Param("id")}
+	if w := c.Query("wait"); w != "" {
+		args["wait"] = w
+	}
+
+	res := runModuleCommandArgs(&hub, "Job", args, c.GetHeader(com.TraceHeader))
+	if !res.Success() {
+		apiFail(c, apiStatus(res.Code), res.Err())
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", res.Data)
+}
+
+func apiCancelJob(c *gin.Context) {
+	hub := GetManager().GetModule("hub")
+	res := runModuleCommandArgs(&hub, "Cancel", com.Args{"id": c.Param("id")}, c.GetHeader(com.TraceHeader))
+	if !res.Success() {
+		apiFail(c, apiStatus(res.Code), res.Err())
+		return
+	}
+	j, _ := GetManager().GetJob(c.Param("id"))
+	c.JSON(http.StatusAccepted, j)
+}
+
+func apiHelp(c *gin.Context) {
+	mc, ok := apiModuleConfig(c)
+	if !ok {
+		return
+	}
+
+	res := runModuleCommandArgs(&mc, "Help", com.Args{"command": c.Param("command")}, c.GetHeader(com.TraceHeader))
+	if !res.Success() {
+		apiFail(c, apiStatus(res.Code), res.Err())
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", res.Data)
+}
+
+func apiModuleRoutes(c *gin.Context) {
+	mods := GetManager().GetModules()
+	routes := []apiRouteSummary{}
+	for _, n := range GetManager().GetConfig().moduleNames() {
+		for _, r := range mods[n].BINDING.PATH {
+			routes = append(routes, apiRouteSummary{FROM: r.FROM, MODULE: n, TO: r.TO})
+		}
+	}
+
+	p, start, end, err := apiPaginate(c, len(routes))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	p.ITEMS = routes[start:end]
+	c.JSON(http.StatusOK, p)
+}
+
+func apiDeployments(c *gin.Context) {
+	deployments := GetManager().GetDeployments()
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deployments[i].STARTED.After(deployments[j].STARTED)
+	})
+
+	p, start, end, err := apiPaginate(c, len(deployments))
+	if err != nil {
+		apiFail(c, http.StatusBadRequest, err)
+		return
+	}
+	p.ITEMS = append([]Deployment{}, deployments[start:end]...)
+	c.JSON(http.StatusOK, p)
+}
+
+func apiOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPIDocument())
+}
+
+//openAPIDocument - Build OpenAPI 3 document from API routes
+func openAPIDocument() map[string]interface{} {
+	paths := map[string]map[string]interface{}{}
+	schemas := map[string]interface{}{}
+	for _, r := range apiRoutes {
+		//GIN :param TO OPENAPI {param}
+		segments := strings.Split(r.path, "/")
+		for i, s := range segments {
+			if strings.HasPrefix(s, ":") {
+				segments[i] = "{" + s[1:] + "}"
+			}
+		}
+		path := apiPrefix + strings.Join(segments, "/")
+
+		var params []map[string]interface{}
+		for _, p := range r.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"required":    p.in == "path",
+				"description": p.description,
+				"schema":      map[string]string{"type": p.typ},
+			})
+		}
+
+		responses := map[string]interface{}{}
+		for code, res := range r.responses {
+			responses[strconv.Itoa(code)] = openAPIResponse(res, schemas)
+		}
+		op := map[string]interface{}{"summary": r.summary, "responses": responses}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if !r.public {
+			responses["401"] = openAPIResponse(apiResponse{"Secret not matching with server", apiError{}}, schemas)
+			op["security"] = []map[string][]string{{"bearer": {}}}
+		}
+
+		if paths[path] == nil {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path][strings.ToLower(r.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "go-woxy", "version": "v1"},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]string{"type": "http", "scheme": "bearer", "description": "Server secret"},
+			},
+		},
+	}
+}
+
+//openAPIResponse - Build OpenAPI response with JSON schema of its body
+func openAPIResponse(res apiResponse, schemas map[string]interface{}) map[string]interface{} {
+	var schema map[string]interface{}
+	if o, ok := res.body.(apiOneOf); ok {
+		var l []interface{}
+		for _, b := range o {
+			l = append(l, openAPISchema(reflect.ValueOf(b), schemas))
+		}
+		schema = map[string]interface{}{"oneOf": l}
+	} else {
+		schema = openAPISchema(reflect.ValueOf(res.body), schemas)
+	}
+	return map[string]interface{}{
+		"description": res.description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+//openAPISchema - Build JSON schema of value as encoding/json writes it
+//Named structs are added to schemas and referenced, except when an interface field holds a value (like page items)
+func openAPISchema(v reflect.Value, schemas map[string]interface{}) map[string]interface{} {
+	if !v.IsValid() {
+		return map[string]interface{}{}
+	}
+	t := v.Type()
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "description": "Nanoseconds"}
+	case t == rawJSONType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return openAPISchema(reflect.Zero(t.Elem()), schemas)
+		}
+		return openAPISchema(v.Elem(), schemas)
+	case reflect.Interface:
+		//ANY VALUE UNLESS SAMPLE HOLDS ONE
+		if v.IsNil() {
+			return map[string]interface{}{}
+		}
+		return openAPISchema(v.Elem(), schemas)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": openAPISchema(reflect.Zero(t.Elem()), schemas)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPISchema(reflect.Zero(t.Elem()), schemas)}
+	case reflect.Struct:
+		return openAPIStruct(v, schemas)
+	}
+	return map[string]interface{}{}
+}
+
+//openAPIStruct - Build object schema of struct exported fields, JSON tag names and omitempty are honored
+func openAPIStruct(v reflect.Value, schemas map[string]interface{}) map[string]interface{} {
+	t := v.Type()
+	inline := t.Name() == ""
+	for i := 0; i < t.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.Interface && !f.IsNil() {
+			inline = true
+		}
+	}
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	if !inline {
+		if _, ok := schemas[t.Name()]; ok {
+			return ref
+		}
+	}
+
+	properties := map[string]interface{}{}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	//REGISTER BEFORE FIELDS SO RECURSIVE TYPES REFERENCE THEMSELVES
+	if !inline {
+		schemas[t.Name()] = schema
+	}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = f.Name
+		}
+		properties[name] = openAPISchema(v.Field(i), schemas)
+		if len(tag) == 1 || tag[1] != "omitempty" {
+			required = append(required, name)
+		}
+	}
+	if required != nil {
+		schema["required"] = required
+	}
+
+	if inline {
+		return schema
+	}
+	return ref
+}
@@ -0,0 +1,226 @@
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Wariie/go-woxy/com"
+	"github.com/gin-gonic/gin"
+)
+
+//apiTestServer - Serve REST API and /cmd on modules a to e, m is online
+func apiTestServer(t *testing.T) *httptest.Server {
+	mods := map[string]ModuleConfig{"m": {NAME: "m", TYPES: "web", STATE: Online}}
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		mods[n] = ModuleConfig{NAME: n, TYPES: "web", STATE: Stopped}
+	}
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, SECRET: "s", MODULES: mods})
+	cp := CommandProcessorImpl{}
+	cp.Init()
+	GetManager().SetCommandProcessor(&cp)
+
+	router := gin.New()
+	serveAPI(router)
+	router.POST("/cmd", command)
+	return httptest.NewServer(router)
+}
+
+//apiTestDo - Send request to test server, returning status and body
+func apiTestDo(t *testing.T, srv *httptest.Server, method string, path string, secret string, body string) (int, []byte) {
+	r, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if secret != "" {
+		r.Header.Set("Authorization", "Bearer "+secret)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, b
+}
+
+func TestAPIAuth(t *testing.T) {
+	srv := apiTestServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		path   string
+		secret string
+		status int
+	}{
+		{"no secret", "/modules", "", http.StatusUnauthorized},
+		{"wrong secret", "/modules", "x", http.StatusUnauthorized},
+		{"wrong query secret", "/modules?secret=x", "", http.StatusUnauthorized},
+		{"bearer secret", "/modules", "s", http.StatusOK},
+		{"query secret", "/modules?secret=s", "", http.StatusOK},
+		{"public document", "/openapi.json", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		status, body := apiTestDo(t, srv, http.MethodGet, apiPrefix+tt.path, tt.secret, "")
+		if status != tt.status {
+			t.Errorf("%s : status = %d, want %d", tt.name, status, tt.status)
+		}
+		if status == http.StatusUnauthorized {
+			var e apiError
+			if err := json.Unmarshal(body, &e); err != nil || e.STATUS != status || e.ERROR == "" {
+				t.Errorf("%s : error body %s", tt.name, body)
+			}
+		}
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	srv := apiTestServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		query  string
+		status int
+		names  string
+	}{
+		{"", http.StatusOK, "a,b,c,d,e,m"},
+		{"?limit=2", http.StatusOK, "a,b"},
+		{"?limit=2&offset=1", http.StatusOK, "b,c"},
+		{"?limit=10&offset=4", http.StatusOK, "e,m"},
+		{"?offset=6", http.StatusOK, ""},
+		{"?offset=100", http.StatusOK, ""},
+		{"?limit=0", http.StatusBadRequest, ""},
+		{"?limit=501", http.StatusBadRequest, ""},
+		{"?limit=x", http.StatusBadRequest, ""},
+		{"?offset=-1", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		status, body := apiTestDo(t, srv, http.MethodGet, apiPrefix+"/modules"+tt.query, "s", "")
+		if status != tt.status {
+			t.Errorf("%s : status = %d, want %d", tt.query, status, tt.status)
+			continue
+		}
+		if status != http.StatusOK {
+			continue
+		}
+
+		var p struct {
+			ITEMS []moduleSummary
+			TOTAL int
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range p.ITEMS {
+			names = append(names, m.NAME)
+		}
+		if got := strings.Join(names, ","); got != tt.names || p.TOTAL != 6 {
+			t.Errorf("%s : items = %s total %d, want %s total 6", tt.query, got, p.TOTAL, tt.names)
+		}
+	}
+}
+
+func TestAPIStatus(t *testing.T) {
+	srv := apiTestServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"unknown module", http.MethodGet, apiPrefix + "/modules/x", "", http.StatusNotFound, ""},
+		{"module", http.MethodGet, apiPrefix + "/modules/m", "", http.StatusOK, ""},
+		{"start online module", http.MethodPost, apiPrefix + "/modules/m/start", "", http.StatusConflict, ""},
+		{"unknown command spec", http.MethodGet, apiPrefix + "/modules/m/commands/Nope", "", http.StatusNotFound, ""},
+		{"command result", http.MethodPost, apiPrefix + "/modules/m/commands/Health", "", http.StatusOK, com.CodeOK},
+		{"command error", http.MethodPost, apiPrefix + "/modules/m/commands/Help?command=Nope", "", http.StatusNotFound, com.CodeNotFound},
+		{"cmd ok", http.MethodPost, "/cmd", `{"Type":"Command","Command":"Health","Target":"m","Secret":"s"}`, http.StatusOK, com.CodeOK},
+		{"cmd conflict", http.MethodPost, "/cmd", `{"Type":"Command","Command":"Start","Target":"m","Secret":"s"}`, http.StatusConflict, com.CodeConflict},
+		{"cmd unknown module", http.MethodPost, "/cmd", `{"Type":"Command","Command":"Health","Target":"x","Secret":"s"}`, http.StatusNotFound, com.CodeNotFound},
+		{"cmd wrong secret", http.MethodPost, "/cmd", `{"Type":"Command","Command":"Health","Hash":"h","Secret":"x"}`, http.StatusUnauthorized, com.CodeUnauthorized},
+		{"cmd bad request", http.MethodPost, "/cmd", `not json`, http.StatusBadRequest, com.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		status, body := apiTestDo(t, srv, tt.method, tt.path, "s", tt.body)
+		if status != tt.status {
+			t.Errorf("%s : status = %d, want %d (%s)", tt.name, status, tt.status, body)
+		}
+		if tt.code == "" {
+			continue
+		}
+		if r, ok := com.ParseCommandResult(string(body)); !ok || r.Code != tt.code {
+			t.Errorf("%s : result %s, want code %s", tt.name, body, tt.code)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := openAPIDocument()
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema map[string]interface{}
+				}
+			}
+		}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{}
+				Required   []string
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, ops := range d.Paths {
+		for method, op := range ops {
+			for code, res := range op.Responses {
+				if len(res.Content["application/json"].Schema) == 0 && path != apiPrefix+"/openapi.json" {
+					t.Errorf("%s %s %s : no response schema", method, path, code)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		schema   string
+		property string
+		want     string
+	}{
+		{"moduleSummary", "STATE", `{"type":"string"}`},
+		{"moduleSummary", "HEALTH", `{"$ref":"#/components/schemas/HealthStatus"}`},
+		{"Job", "CREATED", `{"format":"date-time","type":"string"}`},
+		{"Job", "RESULT", `{"$ref":"#/components/schemas/CommandResult"}`},
+		{"CommandResult", "Results", `{"items":{"$ref":"#/components/schemas/CommandResult"},"type":"array"}`},
+		{"apiError", "STATUS", `{"type":"integer"}`},
+	}
+	for _, tt := range tests {
+		p, ok := d.Components.Schemas[tt.schema].Properties[tt.property]
+		got, _ := json.Marshal(p)
+		if !ok || string(got) != tt.want {
+			t.Errorf("%s.%s schema = %s, want %s", tt.schema, tt.property, got, tt.want)
+		}
+	}
+	if r := strings.Join(d.Components.Schemas["CommandResult"].Required, ","); r != "Code,Command,Duration,Status" {
+		t.Errorf("CommandResult required = %s", r)
+	}
+
+	page := d.Paths[apiPrefix+"/modules"]["get"].Responses["200"].Content["application/json"].Schema
+	got, _ := json.Marshal(page["properties"].(map[string]interface{})["ITEMS"])
+	if want := `{"items":{"$ref":"#/components/schemas/moduleSummary"},"type":"array"}`; string(got) != want {
+		t.Errorf("modules page items schema = %s, want %s", got, want)
+	}
+}
@@ -1,36 +1,40 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	com "github.com/Wariie/go-woxy/com"
+	"github.com/Wariie/go-woxy/tools"
 )
 
 //Command - Command inteface
 type Command interface {
-	Run(r *com.Request, m *ModuleConfig, args ...string) (string, error)
+	Run(ctx context.Context, r *com.Request, m *ModuleConfig, args com.Args) (string, error)
 	Error() error
 	GetResult() string
-	registerExecutor(run func(r *com.Request, m *ModuleConfig, args ...string) (string, error))
+	registerExecutor(run func(ctx context.Context, r *com.Request, m *ModuleConfig, args com.Args) (string, error))
 	GetName() string
+	GetSpec() com.CommandSpec
 }
 
 //ModuleCommand - Command implementation
 type ModuleCommand struct {
-	name     string
+	spec     com.CommandSpec
 	result   string
-	executor func(*com.Request, *ModuleConfig, ...string) (string, error)
+	executor func(context.Context, *com.Request, *ModuleConfig, com.Args) (string, error)
 	err      error
 }
 
 //Run - Command
-func (mc *ModuleCommand) Run(r *com.Request, m *ModuleConfig, args ...string) (string, error) {
-	return mc.executor(r, m, args...)
+func (mc *ModuleCommand) Run(ctx context.Context, r *com.Request, m *ModuleConfig, args com.Args) (string, error) {
+	return mc.executor(ctx, r, m, args)
 }
 
 //Error - Get command execution error
@@ -45,17 +49,22 @@ func (mc *ModuleCommand) GetResult() string {
 
 //GetName - Get command name
 func (mc *ModuleCommand) GetName() string {
-	return mc.name
+	return mc.spec.Name
 }
 
-func (mc *ModuleCommand) registerExecutor(fn func(*com.Request, *ModuleConfig, ...string) (string, error)) {
+//GetSpec - Get command description and parameters
+func (mc *ModuleCommand) GetSpec() com.CommandSpec {
+	return mc.spec
+}
+
+func (mc *ModuleCommand) registerExecutor(fn func(context.Context, *com.Request, *ModuleConfig, com.Args) (string, error)) {
 	mc.executor = fn
 }
 
 //CommandProcessor - CommandProcessor
 type CommandProcessor interface {
-	Register(name string, run func(*com.Request, *ModuleConfig, ...string) (string, error)) bool
-	Run(name string, r com.Request, m ModuleConfig, args ...string) Command
+	Register(spec com.CommandSpec, run func(context.Context, *com.Request, *ModuleConfig, com.Args) (string, error)) bool
+	Run(ctx context.Context, name string, r *com.Request, m *ModuleConfig) com.CommandResult
 }
 
 //CommandProcessorImpl -
@@ -64,123 +73,340 @@ type CommandProcessorImpl struct {
 }
 
 //Register - Register new ModuleCommand in CommandProcessorImpl
-func (cp *CommandProcessorImpl) Register(name string, run func(*com.Request, *ModuleConfig, ...string) (string, error)) {
-	cp.register(name, run)
+func (cp *CommandProcessorImpl) Register(spec com.CommandSpec, run func(context.Context, *com.Request, *ModuleConfig, com.Args) (string, error)) {
+	cp.register(spec, run)
 }
 
-func (cp *CommandProcessorImpl) register(name string, run func(*com.Request, *ModuleConfig, ...string) (string, error)) {
-	c := ModuleCommand{name: name}
+func (cp *CommandProcessorImpl) register(spec com.CommandSpec, run func(context.Context, *com.Request, *ModuleConfig, com.Args) (string, error)) {
+	c := ModuleCommand{spec: spec}
 	c.registerExecutor(run)
 	cp.commands = append(cp.commands, &c)
 }
 
-//Run - Run command in CommandProcessorImpl
-func (cp *CommandProcessorImpl) Run(name string, r *com.Request, m *ModuleConfig, args ...string) (string, error) {
+//Run - Run command in CommandProcessorImpl with arguments of request, result of module is kept as is for forwarded commands
+func (cp *CommandProcessorImpl) Run(ctx context.Context, name string, r *com.Request, m *ModuleConfig) com.CommandResult {
+	start := time.Now()
+	out, err := cp.run(ctx, name, r, m)
+
+	res, ok := com.ParseCommandResult(out)
+	if !ok || err != nil {
+		res = com.NewCommandResult(name, out, err, 0)
+	}
+	res.Command, res.Duration, res.Module = name, time.Since(start), m.NAME
+	return res
+}
+
+//run - Validate request arguments against command spec and run it
+func (cp *CommandProcessorImpl) run(ctx context.Context, name string, r *com.Request, m *ModuleConfig) (string, error) {
+	var args com.Args
+	cr, _ := (*r).(*com.CommandRequest)
+	if cr != nil {
+		args = cr.Args
+	}
+
 	for k := range cp.commands {
-		if cp.commands[k].GetName() == name {
-			c := cp.commands[k]
-			return c.Run(r, m, args...)
+		if c := cp.commands[k]; c.GetName() == name {
+			a, err := c.GetSpec().Validate(args)
+			if err != nil {
+				return "", err
+			}
+			return c.Run(ctx, r, m, a)
 		}
 	}
 
-	//PROCESS MODULE CUSTOM COMMANDS
+	//PROCESS MODULE CUSTOM COMMANDS, MODULES WITHOUT SPEC GET ARGUMENTS AS IS
 	if m.NAME != "hub" {
 		for k := range m.COMMANDS {
-			if m.COMMANDS[k] == name {
-				return defaultForwardCommand(r, m, args...)
+			if m.COMMANDS[k] != name {
+				continue
+			}
+			if spec, ok := m.commandSpec(name); ok && cr != nil {
+				a, err := spec.Validate(args)
+				if err != nil {
+					return "", err
+				}
+				cr.Args = a
 			}
+			return defaultForwardCommand(ctx, r, m, args)
 		}
 	}
 
-	return "Error : Command not found", nil
+	return "", com.NewCommandError(com.CodeNotFound, "command "+name+" not found")
+}
+
+//specs - Get specs of core commands and custom commands of module, sorted by name
+func (cp *CommandProcessorImpl) specs(m *ModuleConfig) []com.CommandSpec {
+	var res []com.CommandSpec
+	for _, c := range cp.commands {
+		res = append(res, c.GetSpec())
+	}
+	if m.NAME != "hub" {
+		for _, n := range m.COMMANDS {
+			spec, ok := m.commandSpec(n)
+			if !ok {
+				spec = com.CommandSpec{Name: n}
+			}
+			res = append(res, spec)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
 }
 
 //Init - Init CommandProcessorImpl with default commands
 func (cp *CommandProcessorImpl) Init() {
-	cp.Register("List", listModuleCommand)
-	cp.Register("Log", logModuleCommand)
-	cp.Register("Performance", performanceModuleCommand)
-	cp.Register("Ping", defaultForwardCommand)
-	cp.Register("Restart", restartModuleCommand)
-	cp.Register("Shutdown", shutdownModuleCommand)
-	cp.Register("Start", startModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Cancel", Description: "Cancel job", Params: []com.Param{
+		{Name: "id", Type: com.ParamString, Description: "Job id", Required: true}}}, cancelJobModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Deploy", Description: "Deploy module ref from its git repository"}, deployModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Deployments", Description: "List deployments of module"}, deploymentsModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Describe", Description: "Get command description and parameters", Params: []com.Param{
+		{Name: "command", Type: com.ParamString, Description: "Command name", Required: true}}}, helpModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Health", Description: "Get module health"}, healthModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Help", Description: "List commands of module, or describe one", Params: []com.Param{
+		{Name: "command", Type: com.ParamString, Description: "Command name"}}}, helpModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Job", Description: "Get job progress and result", Params: []com.Param{
+		{Name: "id", Type: com.ParamString, Description: "Job id", Required: true},
+		{Name: "wait", Type: com.ParamDuration, Description: "Wait for job to finish, at most 1m"}}}, jobModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Jobs", Description: "List jobs of module"}, jobsModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Kill", Description: "Kill module process group"}, killModuleCommand)
+	cp.Register(com.CommandSpec{Name: "List", Description: "List modules"}, listModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Log", Description: "Get module log lines", Params: []com.Param{
+		{Name: "grep", Type: com.ParamString, Description: "Lines matching regular expression"},
+		{Name: "level", Type: com.ParamString, Description: "Lines at or above level"},
+		{Name: "request_id", Type: com.ParamString, Description: "Lines of a request"},
+		{Name: "since", Type: com.ParamString, Description: "Lines since duration or RFC3339 time"},
+		{Name: "tail", Type: com.ParamInt, Description: "Last lines only"}}}, logModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Performance", Description: "Get module CPU and memory samples", Params: []com.Param{
+		{Name: "from", Type: com.ParamString, Description: "Range start, duration ago or RFC3339 time (default : 1h)"},
+		{Name: "to", Type: com.ParamString, Description: "Range end, duration ago or RFC3339 time (default : now)"}}}, performanceModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Ping", Description: "Check module answers"}, defaultForwardCommand)
+	cp.Register(com.CommandSpec{Name: "Restart", Description: "Stop and start module"}, restartModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Rollback", Description: "Restart module on its previous commit"}, rollbackModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Shutdown", Description: "Ask module to shut down"}, shutdownModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Start", Description: "Start module", Params: []com.Param{
+		{Name: "module", Type: com.ParamString, Description: "Module to start (default : target module)"}}}, startModuleCommand)
+	cp.Register(com.CommandSpec{Name: "Stop", Description: "Stop module"}, stopModuleCommand)
+}
+
+//runModuleCommand - Run command on module from core, as child of traceparent, and save module changes
+func runModuleCommand(mc *ModuleConfig, command string, content string, traceparent string) com.CommandResult {
+	return runModuleRequest(context.Background(), mc, com.CommandRequest{Command: command, Content: content, Name: mc.NAME, Type: "Command"}, traceparent)
+}
+
+//runModuleCommandArgs - Run command with arguments on module from core, as child of traceparent, and save module changes
+func runModuleCommandArgs(mc *ModuleConfig, command string, args com.Args, traceparent string) com.CommandResult {
+	return runModuleRequest(context.Background(), mc, com.CommandRequest{Args: args, Command: command, Name: mc.NAME, Type: "Command"}, traceparent)
+}
+
+//runModuleRequest - Run command request on module, hash is set to module one so forwarded commands are accepted
+func runModuleRequest(ctx context.Context, mc *ModuleConfig, cr com.CommandRequest, traceparent string) com.CommandResult {
+	span := com.StartSpan("cmd "+cr.Command, com.SpanServer, traceparent)
+	span.Set("woxy.command", cr.Command)
+	span.Set("woxy.module", mc.NAME)
+
+	cr.Hash = mc.PK
+	cr.Traceparent = span.Context().Traceparent()
+	var r com.Request = &cr
+	res := GetManager().GetCommandProcessor().Run(ctx, cr.Command, &r, mc)
+	span.Set("woxy.code", res.Code)
+	span.Finish(res.Err())
+	GetManager().SaveModuleChanges(mc)
+	return res
 }
 
 /* ---------------------------DEFAULT COMMANDS----------------------------*/
 
-func commandsModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	return defaultForwardCommand(r, mc, args...)
+func commandsModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	return defaultForwardCommand(ctx, r, mc, args)
 }
 
-func defaultForwardCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	return com.SendRequest(mc.GetServer("/cmd"), *r, false)
+func defaultForwardCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	out, err := com.SendRequest(mc.GetServer("/cmd"), *r, false)
+	if err != nil {
+		return "", com.NewCommandError(com.CodeUnavailable, err.Error())
+	}
+	return out, nil
+}
+
+//moduleSucceeded - Check module answer to command, modules without results envelope answer text containing expected
+func moduleSucceeded(out string, expected string) bool {
+	if r, ok := com.ParseCommandResult(out); ok {
+		return r.Success()
+	}
+	return strings.Contains(out, expected)
+}
+
+func deployModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	if mc.sourceType() != gitSource {
+		return "", com.NewCommandError(com.CodeConflict, "Only git modules can be deployed")
+	}
+
+	d := Deployment{
+		BRANCH:     mc.EXE.REF,
+		ID:         tools.String(12),
+		MODULES:    []string{mc.NAME},
+		REPOSITORY: mc.EXE.SRC,
+		STARTED:    time.Now(),
+		STATUS:     DeployPending,
+	}
+	GetManager().SaveDeployment(d)
+	go d.run(GetManager().GetConfig().WEBHOOK.TIMEOUT)
+
+	rb, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
 }
 
-func listModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	rb, err := json.Marshal(GetManager().GetConfig().MODULES)
+func deploymentsModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	var res []Deployment
+	for _, d := range GetManager().GetDeployments() {
+		if mc.NAME == "hub" || d.hasModule(mc.NAME) {
+			res = append(res, d)
+		}
+	}
+
+	rb, err := json.Marshal(res)
 	if err != nil {
-		return "Error :", err
+		return "", err
 	}
 	return string(rb), nil
 }
 
-func logModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	return mc.GetLog(), nil
+func healthModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	rb, err := json.Marshal(GetManager().GetHealth(mc.NAME))
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
 }
 
-func shutdownModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	response, err := defaultForwardCommand(r, mc, args...)
-	if strings.Contains(response, "SHUTTING DOWN "+mc.NAME) || (err != nil && strings.Contains(err.Error(), "An existing connection was forcibly closed by the remote host")) {
-		response = "Success"
-		mc.STATE = Stopped
-		GetManager().GetSupervisor().Remove(mc.NAME)
-	} else {
-		response = ""
+func killModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	if err := mc.Kill(); err != nil {
+		return "", err
 	}
-	return response, err
+	return "Success", nil
+}
+
+func listModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	rb, err := json.Marshal(GetManager().GetModules())
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
 }
 
-func performanceModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	c, ra := mc.GetPerf()
-	return "CPU/RAM : " + fmt.Sprintf("%f", c) + "/" + fmt.Sprintf("%f", ra), nil
+func logModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	q, err := parseLogQuery(commandQuery(r, args))
+	if err != nil {
+		return "", com.NewCommandError(com.CodeBadRequest, err.Error())
+	}
+	return mc.GetLog(q), nil
 }
 
-func restartModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
-	response := ""
-	cr := (*r).(*com.CommandRequest)
-	cr.Command = "Shutdown"
-	rqtS, err := com.SendRequest(mc.GetServer("/cmd"), cr, false)
-	if strings.Contains(rqtS, "SHUTTING DOWN "+mc.NAME) || (err != nil && strings.Contains(err.Error(), "An existing connection was forcibly closed by the remote host")) {
+//commandQuery - Get query of arguments, or of request content for callers without arguments
+func commandQuery(r *com.Request, args com.Args) string {
+	if len(args) == 0 {
+		return (*r).(*com.CommandRequest).Content
+	}
+	v := url.Values{}
+	for k, a := range args {
+		v.Set(k, a)
+	}
+	return v.Encode()
+}
 
-		if mc.pid != 0 {
-			for checkModuleRunning(*mc) {
-				time.Sleep(time.Second)
+func helpModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	var res interface{} = GetManager().GetCommandProcessor().specs(mc)
+	if name := args.String("command"); name != "" {
+		res = nil
+		for _, s := range GetManager().GetCommandProcessor().specs(mc) {
+			if s.Name == name {
+				res = s
 			}
 		}
+		if res == nil {
+			return "", com.NewCommandError(com.CodeNotFound, "command "+name+" not found")
+		}
+	}
+
+	rb, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
+}
+
+func shutdownModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	response, err := defaultForwardCommand(ctx, r, mc, args)
+	if moduleSucceeded(response, "SHUTTING DOWN "+mc.NAME) || (err != nil && strings.Contains(err.Error(), "An existing connection was forcibly closed by the remote host")) {
 		mc.STATE = Stopped
+		GetManager().GetSupervisor().Remove(mc.NAME)
+		return "Success", nil
+	} else if err != nil {
+		return "", err
+	} else if res, ok := com.ParseCommandResult(response); ok {
+		return "", res.Err()
+	}
+	return "", errors.New(response)
+}
 
-		if err := mc.Setup(GetManager().GetRouter(), false); err != nil {
-			response += "Error :" + err.Error()
-			log.Println(err)
-		} else {
-			response += "Success"
-			mc.STATE = Stopped
-		}
-	} else {
-		response += "Error :" + rqtS
-		if err != nil {
-			response += " - " + err.Error()
-		}
+func performanceModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	from, to, err := parsePerfQuery(commandQuery(r, args))
+	if err != nil {
+		return "", com.NewCommandError(com.CodeBadRequest, err.Error())
+	}
+
+	rb, err := json.Marshal(GetManager().GetPerfStore().Query(mc.NAME, from, to))
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
+}
+
+func restartModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	if err := mc.Stop(ctx); err != nil {
+		return "", err
+	}
+	if err := mc.Setup(ctx, GetManager().GetRouter(), false); err != nil {
+		log.Println(err)
+		return "", err
+	}
+	mc.STATE = Stopped
+	return "Success", nil
+}
+
+func rollbackModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	n := len(mc.PREVIOUS_COMMITS)
+	if n == 0 {
+		return "", com.NewCommandError(com.CodeConflict, "No previous commit to rollback to")
+	}
+
+	//PIN PREVIOUS COMMIT SO RESTART DOWNLOAD DOES NOT MOVE FORWARD AGAIN, REF IS KEPT FOR NEXT DEPLOY
+	pinned, commit, previous := mc.PINNED_COMMIT, mc.COMMIT, mc.PREVIOUS_COMMITS
+	mc.PINNED_COMMIT = mc.PREVIOUS_COMMITS[n-1]
+	mc.COMMIT = mc.PREVIOUS_COMMITS[n-1]
+	mc.PREVIOUS_COMMITS = mc.PREVIOUS_COMMITS[:n-1]
+
+	response, err := restartModuleCommand(ctx, r, mc, args)
+	if err != nil {
+		mc.PINNED_COMMIT, mc.COMMIT, mc.PREVIOUS_COMMITS = pinned, commit, previous
 	}
 	return response, err
 }
 
-func startModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (string, error) {
+func startModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
 
-	response := ""
-	mods := GetManager().GetConfig().MODULES
+	mods := GetManager().GetModules()
 	var mo ModuleConfig
-	c := (*r).(*com.CommandRequest).Content
+	c := args.String("module")
+	if c == "" {
+		c = (*r).(*com.CommandRequest).Content
+	}
+	if c == "" {
+		c = mc.NAME
+	}
 	for m := range mods {
 		if m == c {
 			mo = mods[m]
@@ -188,16 +414,31 @@ func startModuleCommand(r *com.Request, mc *ModuleConfig, args ...string) (strin
 		}
 	}
 
-	var err error
-	if mo.STATE != Online {
-		err = mo.Setup(GetManager().GetRouter(), false)
-		if err == nil {
-			response += "Success"
-		} else {
-			response += err.Error()
-		}
-	} else {
-		err = errors.New("Module already Online")
+	if mo.NAME == "" {
+		return "", com.NewCommandError(com.CodeNotFound, "Module "+c+" not found")
+	} else if mo.STATE.running() {
+		return "", com.NewCommandError(com.CodeConflict, "Module already "+string(mo.STATE))
 	}
-	return response, err
+
+	//MODULE COMMAND RUNS ON IS SAVED BY CALLER, ANOTHER ONE HERE SO ITS FAILED STATE IS NOT SEEN AGAIN
+	target := &mo
+	if mo.NAME == mc.NAME {
+		target = mc
+	}
+	target.STATE = Loading
+	err := target.Setup(ctx, GetManager().GetRouter(), false)
+	if target != mc {
+		GetManager().SaveModuleChanges(target)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "Success", nil
+}
+
+func stopModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	if err := mc.Stop(ctx); err != nil {
+		return "", err
+	}
+	return "Success", nil
 }
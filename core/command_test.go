@@ -0,0 +1,27 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+func TestStartModuleCommandRunning(t *testing.T) {
+	tests := []struct {
+		state ModuleState
+		code  string
+	}{
+		{Online, com.CodeConflict},
+		{LimitExceeded, com.CodeConflict},
+	}
+	for _, tt := range tests {
+		GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{"m": {NAME: "m", STATE: tt.state}}})
+		mc := GetManager().GetModule("m")
+		var r com.Request = &com.CommandRequest{}
+		_, err := startModuleCommand(context.Background(), &r, &mc, com.Args{})
+		if ce, ok := err.(*com.CommandError); !ok || ce.Code != tt.code {
+			t.Errorf("%s : Start error = %v, want code %s", tt.state, err, tt.code)
+		}
+	}
+}
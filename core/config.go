@@ -2,15 +2,20 @@ package core
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha256"
 	"crypto/tls"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/Wariie/go-woxy/tools"
 	"github.com/gin-gonic/gin"
@@ -19,6 +24,21 @@ import (
 
 func (c *Config) loadConfig(configPath string) {
 
+	if err := c.parseConfig(configPath); err != nil {
+		log.Fatalf("GO-WOXY Core - Error in config file : %v", err)
+	}
+
+	fmt.Println("GO-WOXY Core - Config file readed")
+}
+
+//ValidateConfig - Parse and check config file, without starting server or modules
+func ValidateConfig(configPath string) error {
+	var c Config
+	return c.parseConfig(configPath)
+}
+
+func (c *Config) parseConfig(configPath string) error {
+
 	if configPath == "" {
 		//EMPTY CONFIG FILE PATH
 		//TRY DEFAULT cfg.yml
@@ -28,23 +48,43 @@ func (c *Config) loadConfig(configPath string) {
 	//READ CONFIG FILE
 	data, err := ioutil.ReadFile(configPath)
 	if err != nil {
-		log.Fatalf("GO-WOXY Core - Error reading config file : %v", err)
+		return errors.New("reading config file : " + err.Error())
 	}
 
 	//PARSE CONFIG FILE
-	err = yaml.Unmarshal(data, &c)
-	if err != nil || c.NAME == "" {
-		log.Fatalf("GO-WOXY Core - Error parsing config file %v", err)
+	if err = yaml.Unmarshal(data, &c); err != nil {
+		return errors.New("parsing config file : " + err.Error())
+	} else if c.NAME == "" {
+		return errors.New("name is required")
 	}
 
-	c.checkServer()
+	if err := c.checkServer(); err != nil {
+		return err
+	}
 
-	c.checkModules()
+	c.checkLog()
 
-	fmt.Println("GO-WOXY Core - Config file readed")
+	if err := c.checkAccessLog(); err != nil {
+		return err
+	}
+
+	c.checkWebhook()
+
+	c.checkMetrics()
+
+	c.checkDashboard()
+
+	c.checkTracing()
+
+	if err := c.checkModules(); err != nil {
+		return err
+	}
+
+	return c.checkDependencies()
 }
 
-func (c *Config) checkModules() {
+func (c *Config) checkModules() error {
+	ports := map[string]string{c.SERVER.PORT: "go-woxy server"}
 	for k := range c.MODULES {
 		m := c.MODULES[k]
 		m.NAME = k
@@ -62,11 +102,34 @@ func (c *Config) checkModules() {
 			m.BINDING.ADDRESS = "127.0.0.1"
 		}
 
+		//SOURCE WITHOUT KNOWN FORM IS NEITHER CLONED NOR COPIED
+		if !m.EXE.REMOTE && m.sourceType() == unknownSource {
+			return fmt.Errorf("unknown src %s of module %s : use a git URL, a git@ address, an archive or a local path starting with /, ./ or ../", m.EXE.SRC, k)
+		}
+
+		//MODULE IN ITS OWN NETWORK NAMESPACE COULD NOT REGISTER TO HUB NOR BE REACHED BY PROXY
+		if m.EXE.ISOLATION.NETWORK {
+			return fmt.Errorf("network isolation of module %s is not supported : module must reach go-woxy hub", k)
+		}
+
+		if err := m.HEALTH.check(); err != nil {
+			return fmt.Errorf("health config of module %s : %v", k, err)
+		}
+
+		//CHECK PORT CONFLICTS BEFORE ANY MODULE START
+		if p := m.BINDING.PORT; p != "" && !strings.Contains(m.TYPES, "bind") {
+			if o, ok := ports[p]; ok {
+				return fmt.Errorf("port %s of module %s already used by %s", p, k, o)
+			}
+			ports[p] = k
+		}
+
 		c.MODULES[k] = m
 	}
+	return nil
 }
 
-func (c *Config) checkServer() {
+func (c *Config) checkServer() error {
 
 	//CHECK IP IF NOT PRESENT -> DEFAULT LOCALHOST
 	if c.SERVER.ADDRESS == "" {
@@ -77,6 +140,99 @@ func (c *Config) checkServer() {
 	if c.SERVER.PORT == "" {
 		c.SERVER.PORT = "2000"
 	}
+
+	//CHECK PERFORMANCE SAMPLING INTERVAL IF NOT PRESENT -> DEFAULT 10s
+	if c.PERF.INTERVAL <= 0 {
+		c.PERF.INTERVAL = 10 * time.Second
+	}
+
+	//CHECK MODULE PORT RANGE IF NOT PRESENT -> DEFAULT 4300-4399
+	if c.PORTS.FROM == 0 && c.PORTS.TO == 0 {
+		c.PORTS = PortRangeConfig{FROM: 4300, TO: 4399}
+	} else if c.PORTS.TO < c.PORTS.FROM {
+		return fmt.Errorf("invalid ports range %d-%d", c.PORTS.FROM, c.PORTS.TO)
+	}
+	return nil
+}
+
+func (c *Config) checkWebhook() {
+
+	//CHECK WEBHOOK PATH IF NOT PRESENT -> DEFAULT /hooks/deploy
+	if c.WEBHOOK.PATH == "" {
+		c.WEBHOOK.PATH = "/hooks/deploy"
+	}
+}
+
+func (c *Config) checkMetrics() {
+
+	//CHECK METRICS PATH IF NOT PRESENT -> DEFAULT /metrics
+	if c.METRICS.PATH == "" {
+		c.METRICS.PATH = "/metrics"
+	}
+}
+
+func (c *Config) checkDashboard() {
+
+	//CHECK DASHBOARD PATH IF NOT PRESENT -> DEFAULT /dashboard
+	if c.DASHBOARD.PATH == "" {
+		c.DASHBOARD.PATH = "/dashboard"
+	}
+	c.DASHBOARD.PATH = "/" + strings.Trim(c.DASHBOARD.PATH, "/")
+	if c.DASHBOARD.HTPASSWD == "" {
+		c.DASHBOARD.HTPASSWD = ".htpasswd"
+	}
+}
+
+func (c *Config) checkTracing() {
+
+	//CHECK TRACES FILE IF NOT PRESENT -> DEFAULT ./logs/traces.jsonl
+	if c.TRACING.EXPORTER == "file" && c.TRACING.PATH == "" {
+		c.TRACING.PATH = filepath.Join(c.LOG.PATH, "traces.jsonl")
+	}
+}
+
+func (c *Config) checkAccessLog() error {
+
+	//CHECK ACCESS LOG FORMAT IF NOT PRESENT -> DEFAULT console
+	switch c.ACCESS_LOG.FORMAT {
+	case "":
+		c.ACCESS_LOG.FORMAT = AccessLogConsole
+	case AccessLogCombined, AccessLogCommon, AccessLogConsole, AccessLogJSON:
+	default:
+		return errors.New("unknown access log format " + c.ACCESS_LOG.FORMAT)
+	}
+
+	//CHECK ROTATION IF NOT PRESENT -> DEFAULT 10 MB AND 5 FILES
+	if c.ACCESS_LOG.MAX_SIZE <= 0 {
+		c.ACCESS_LOG.MAX_SIZE = 10
+	}
+	if c.ACCESS_LOG.MAX_FILES <= 0 {
+		c.ACCESS_LOG.MAX_FILES = 5
+	}
+	return nil
+}
+
+func (c *Config) checkLog() {
+
+	//CHECK LOG PATH IF NOT PRESENT -> DEFAULT ./logs
+	if c.LOG.PATH == "" {
+		c.LOG.PATH = "./logs"
+	}
+
+	//CHECK RING BUFFER SIZE IF NOT PRESENT -> DEFAULT 1000 LINES
+	if c.LOG.BUFFER <= 0 {
+		c.LOG.BUFFER = 1000
+	}
+
+	//CHECK MAX SIZE IF NOT PRESENT -> DEFAULT 10 MB
+	if c.LOG.MAX_SIZE <= 0 {
+		c.LOG.MAX_SIZE = 10
+	}
+
+	//CHECK MAX FILES IF NOT PRESENT -> DEFAULT 5 ROTATED FILES
+	if c.LOG.MAX_FILES <= 0 {
+		c.LOG.MAX_FILES = 5
+	}
 }
 
 func (c *Config) loadModules() {
@@ -89,27 +245,26 @@ func (c *Config) loadModules() {
 		os.Exit(1)
 	}
 
+	//START IN DEPENDENCY ORDER, DEPENDENTS WAIT IN LOADING STATE
 	Router := GetManager().router
-	for k := range c.MODULES {
-		mod := c.MODULES[k]
-		err := mod.Setup(Router, true)
+	for _, k := range c.moduleNames() {
+		mod := GetManager().GetModule(k)
+		err := mod.Setup(context.Background(), Router, true)
 		if err != nil {
-			log.Fatalln("GO-WOXY Core - Error setup module ", mod.NAME, " : ", err)
+			log.Println("GO-WOXY Core - Error setup module ", mod.NAME, " : ", err)
 		}
-		c.MODULES[k] = mod
+		GetManager().SaveModuleChanges(&mod)
 	}
 
 	//ADD HUB MODULE FOR COMMAND GESTURE
-	c.MODULES["hub"] = ModuleConfig{NAME: "hub", PK: "hub"}
-
-	GetManager().router = Router
-	GetManager().config = c
+	GetManager().SaveModuleChanges(&ModuleConfig{NAME: "hub", PK: "hub"})
 }
 
 func initSupervisor() {
 	s := Supervisor{}
 	GetManager().SetSupervisor(&s)
 	go s.Supervise()
+	go s.Sample(GetManager().GetConfig().PERF.INTERVAL)
 }
 
 func (c *Config) configAndServe(router *gin.Engine) error {
@@ -143,7 +298,7 @@ func (c *Config) configAndServe(router *gin.Engine) error {
 func (c *Config) generateSecret() {
 	if c.SECRET == "" {
 		b := []byte(tools.String(64))
-		err := ioutil.WriteFile(".secret", b, 0644)
+		err := ioutil.WriteFile(".secret", b, 0600)
 		if err != nil {
 			log.Fatalln("GO-WOXY Core - Error trying create secret file :", err)
 		}
@@ -183,8 +338,24 @@ func (c *Config) motd() {
 	fmt.Println("------------------------------------------------------------ ")
 }
 
+//moduleNames - Get configured module names in start order, hub excluded
+func (c *Config) moduleNames() []string {
+	if len(c.order) > 0 {
+		return append([]string(nil), c.order...)
+	}
+
+	var names []string
+	for k := range c.MODULES {
+		if k != "hub" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func searchModWithHash(hash string) ModuleConfig {
-	mods := GetManager().config.MODULES
+	mods := GetManager().GetModules()
 	for i := range mods {
 		if mods[i].PK == hash {
 			return mods[i]
@@ -2,16 +2,18 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"reflect"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Wariie/go-woxy/com"
-	"github.com/gin-contrib/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	zLog "github.com/rs/zerolog/log"
@@ -24,7 +26,25 @@ func launchServer() {
 	GetManager().router.POST("/connect", connect)
 	GetManager().router.POST("/cmd", command)
 
-	log.Fatalln("GO-WOXY Core - Error ListenAndServer :", GetManager().config.configAndServe(GetManager().router))
+	//LOG STREAMING ENDPOINTS
+	GetManager().router.GET("/logs/stream", logStream)
+	GetManager().router.GET("/logs/ws", logWebSocket)
+
+	//REDEPLOY WEBHOOK ONLY WHEN PAYLOADS CAN BE AUTHENTICATED
+	if GetManager().GetConfig().WEBHOOK.secret() != "" {
+		GetManager().router.POST(GetManager().GetConfig().WEBHOOK.PATH, webhook)
+	}
+
+	//REST MANAGEMENT API
+	serveAPI(GetManager().router)
+
+	//ADMIN DASHBOARD
+	GetManager().GetConfig().serveDashboard(GetManager().router)
+
+	//PROMETHEUS METRICS ENDPOINT
+	GetManager().GetConfig().serveMetrics(GetManager().router)
+
+	log.Fatalln("GO-WOXY Core - Error ListenAndServer :", GetManager().GetConfig().configAndServe(GetManager().router))
 }
 
 func initCore() {
@@ -42,10 +62,10 @@ func initCore() {
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
-	router.Use(logger.SetLogger(), gin.Recovery())
+	router.Use(requestID(), accessLogger(), gin.Recovery())
 	router.LoadHTMLGlob("ressources/*/*")
 	router.NoRoute(func(c *gin.Context) {
-		c.HTML(404, "404.html", nil)
+		c.HTML(404, "404.html", gin.H{"request_id": c.GetString(requestIDKey)})
 	})
 
 	cp := CommandProcessorImpl{}
@@ -69,11 +89,17 @@ func LaunchCore(configPath string) {
 	c.generateSecret()
 
 	// SAVE CONFIG
-	GetManager().config = &c
+	GetManager().SetState(&c)
+
+	// EXPORT SPANS
+	c.initTracing()
 
 	// START MODULE SUPERVISOR
 	initSupervisor()
 
+	// STOP MODULES IN REVERSE DEPENDENCY ORDER ON EXIT
+	go waitSignal()
+
 	// STEP 4 LOAD MODULES
 	go c.loadModules()
 
@@ -81,6 +107,16 @@ func LaunchCore(configPath string) {
 	launchServer()
 }
 
+func waitSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	s := <-sig
+
+	fmt.Println("GO-WOXY Core - Received", s, "- Stopping modules")
+	GetManager().GetConfig().stopModules()
+	os.Exit(0)
+}
+
 func connect(context *gin.Context) {
 
 	var cr com.ConnexionRequest
@@ -89,7 +125,7 @@ func connect(context *gin.Context) {
 	cr.Decode(buf.Bytes())
 
 	var modC ModuleConfig
-	modC = GetManager().config.MODULES[cr.Name]
+	modC = GetManager().GetModule(cr.Name)
 
 	if reflect.DeepEqual(modC, ModuleConfig{}) {
 		errMsg := "GO-WOXY Core - Error reading ConnexionRequest"
@@ -136,10 +172,12 @@ func registerModule(m *ModuleConfig, cr *com.ConnexionRequest) bool {
 	m.pid = pid
 	m.PK = cr.ModHash
 	m.COMMANDS = cr.CustomCommands
+	m.COMMAND_SPECS = cr.CommandSpecs
 	m.STATE = Online
 
-	if m.BINDING.PORT != "" {
-		cr.Port = m.BINDING.PORT
+	//PORT ALLOCATED BY CORE STAYS OUT OF MODULE CONFIG SO IT GOES BACK TO RANGE ON EXIT
+	if p := m.port(); p != "" {
+		cr.Port = p
 	} else {
 		m.BINDING.PORT = cr.Port
 	}
@@ -161,10 +199,10 @@ func registerModule(m *ModuleConfig, cr *com.ConnexionRequest) bool {
 	try := 0
 	r := false
 	for {
-		res, e := cp.Run("Ping", &p, m, "")
-		log.Print(res, e)
+		res := cp.Run(context.Background(), "Ping", &p, m)
+		log.Print(res.Text(), res.Err())
 
-		if res != "" && err == nil {
+		if res.Success() {
 			r = true
 			break
 		} else if try > 15 {
@@ -185,26 +223,39 @@ func registerModule(m *ModuleConfig, cr *com.ConnexionRequest) bool {
 
 // Command - Access point to handle module commands
 func command(c *gin.Context) {
-	log.Print("GO-WOXY Core - Command")
+	log.Print("GO-WOXY Core - Command request_id=" + c.GetString(requestIDKey))
 	t, b := com.GetCustomRequestType(c.Request)
 
 	from := c.Request.RemoteAddr
 
 	//TODO HANDLE ACCESS WITH CREDENTIALS
-	response := ""
+	var response com.CommandResult
 	action := ""
 
 	rs := strings.TrimSuffix(t["Secret"], "\n\t ") == strings.TrimSuffix(GetManager().GetConfig().SECRET, "\n\t ")
 
 	// IF ERROR READING DATA
 	if t["error"] == "error" {
-		response = "Error reading Request"
+		response = com.NewCommandResult("", "", com.NewCommandError(com.CodeBadRequest, "Error reading Request"), 0)
+	} else if (t["Target"] != "" || t["Selector"] != "") && rs && t["Type"] == "Command" {
+		//MODULES ADDRESSED BY NAME OR LABELS
+		var cr com.CommandRequest
+		cr.Decode(b)
+		action += "To " + cr.Target + " [" + cr.Selector + "] - Command [ " + cr.Command + " ]"
+		traceparent := c.GetHeader(com.TraceHeader)
+		if cr.Async {
+			response = startBroadcastJob(cr, traceparent)
+		} else {
+			response = broadcastCommand(cr, func(mc *ModuleConfig, cr com.CommandRequest) com.CommandResult {
+				return runModuleRequest(context.Background(), mc, cr, traceparent)
+			})
+		}
 	} else if t["Hash"] != "" && rs {
 		//GET MOD WITH HASH
 		mc := searchModWithHash(t["Hash"])
 
 		if mc.NAME == "error" {
-			response = "Error module not found"
+			response = com.NewCommandResult(t["Command"], "", com.NewCommandError(com.CodeNotFound, "Error module not found"), 0)
 		} else {
 			action += "To " + mc.NAME + " - "
 
@@ -213,30 +264,57 @@ func command(c *gin.Context) {
 			case "Command":
 				var cr com.CommandRequest
 				cr.Decode(b)
-				cp := GetManager().GetCommandProcessor()
-				var c interface{}
-				c = &cr
-				p := (c).(com.Request)
-				res, e := cp.Run(cr.Command, &p, &mc, "")
-				response += res
-				if e != nil {
-					response += e.Error()
+
+				if cr.Async {
+					response = startModuleJob(&mc, cr, c.GetHeader(com.TraceHeader))
+				} else {
+					response = runModuleRequest(context.Background(), &mc, cr, c.GetHeader(com.TraceHeader))
 				}
 				action += "Command [ " + cr.Command + " ]"
+			default:
+				response = com.NewCommandResult("", "", com.NewCommandError(com.CodeBadRequest, "Unknown request type "+t["Type"]), 0)
 			}
-			GetManager().SaveModuleChanges(&mc)
 		}
 	} else {
-		if t["Hash"] == "" {
-			response = "Empty Hash : Try to start module"
+		if t["Hash"] == "" && t["Target"] == "" && t["Selector"] == "" {
+			response = com.NewCommandResult(t["Command"], "", com.NewCommandError(com.CodeBadRequest, "Empty Hash or Target : Try to start module"), 0)
 		} else if !rs {
-			response = "Secret not matching with server"
+			response = com.NewCommandResult(t["Command"], "", com.NewCommandError(com.CodeUnauthorized, "Secret not matching with server"), 0)
 		} else {
-			response = "Unknown error"
+			response = com.NewCommandResult(t["Command"], "", com.NewCommandError(com.CodeBadRequest, "Unknown error"), 0)
 		}
 	}
 
-	action += " - Result : " + response
-	log.Println(" from", from, ":", action, "-", response)
-	c.String(200, "%s", response)
+	action += " - Result : " + response.Status + " " + response.Code
+	log.Println(" from", from, ":", action, "-", response.Text(), "request_id="+c.GetString(requestIDKey))
+	c.JSON(apiStatus(response.Code), response)
+}
+
+//broadcastCommand - Run command on modules of request target and selector with run, one after another in start order
+//Result is module one when a single module is named, module results are in Results otherwise
+func broadcastCommand(cr com.CommandRequest, run func(mc *ModuleConfig, cr com.CommandRequest) com.CommandResult) com.CommandResult {
+	start := time.Now()
+	if cr.Target == "" {
+		cr.Target = "*"
+	}
+	mods, err := GetManager().GetConfig().selectModules(cr.Target, cr.Selector)
+	if err != nil {
+		return com.NewCommandResult(cr.Command, "", err, time.Since(start))
+	}
+
+	if cr.Selector == "" && cr.Target != "*" && !strings.Contains(cr.Target, ",") {
+		return run(&mods[0], cr)
+	}
+
+	res := com.CommandResult{Code: com.CodeOK, Command: cr.Command, Results: []com.CommandResult{}, Status: com.StatusSuccess}
+	for i := range mods {
+		r := run(&mods[i], cr)
+		if !r.Success() {
+			res.Code, res.Status = com.CodePartial, com.StatusError
+			res.Message = "Command failed on some modules"
+		}
+		res.Results = append(res.Results, r)
+	}
+	res.Duration = time.Since(start)
+	return res
 }
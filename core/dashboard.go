@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Wariie/go-woxy/com"
+	auth "github.com/abbot/go-http-auth"
+	"github.com/gin-gonic/gin"
+)
+
+//DashboardConfig - Admin web dashboard served by core
+type DashboardConfig struct {
+	ENABLED  bool
+	HTPASSWD string
+	PATH     string
+}
+
+//dashboardHeader - Header required on dashboard actions, browsers never send it cross-site without preflight
+const dashboardHeader = "X-Woxy-Dashboard"
+
+//dashboardCommands - Commands dashboard can run on a module
+var dashboardCommands = map[string]bool{
+	"Deploy":      true,
+	"Deployments": true,
+	"Health":      true,
+	"Kill":        true,
+	"Log":         true,
+	"Performance": true,
+	"Restart":     true,
+	"Rollback":    true,
+	"Start":       true,
+	"Stop":        true,
+}
+
+//serveDashboard - Serve dashboard behind basic auth of htpasswd file
+func (c *Config) serveDashboard(router *gin.Engine) {
+	d := c.DASHBOARD
+	if !d.ENABLED {
+		return
+	}
+
+	htpasswd := auth.HtpasswdFileProvider(d.HTPASSWD)
+	authenticator := auth.NewBasicAuthenticator("go-woxy dashboard", htpasswd)
+	g := router.Group(d.PATH, BasicAuth(authenticator))
+	g.GET("", dashboard)
+	g.GET("/modules", dashboardModules)
+	g.POST("/modules/:name/:command", dashboardCommand)
+	fmt.Println("GO-WOXY Core - Serving dashboard at " + d.PATH)
+}
+
+//dashboard - Dashboard page
+func dashboard(c *gin.Context) {
+	cfg := GetManager().GetConfig()
+	c.HTML(http.StatusOK, "dashboard.html", gin.H{"name": cfg.NAME, "path": cfg.DASHBOARD.PATH, "header": dashboardHeader})
+}
+
+//dashboardModules - Modules summaries in start order
+func dashboardModules(c *gin.Context) {
+	mods := GetManager().GetModules()
+	res := []moduleSummary{}
+	for _, n := range GetManager().GetConfig().moduleNames() {
+		m := mods[n]
+		res = append(res, m.summary())
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+//dashboardCommand - Run command on module through command processor, request body is command content
+func dashboardCommand(c *gin.Context) {
+	if c.GetHeader(dashboardHeader) == "" {
+		c.String(http.StatusForbidden, "Missing %s header", dashboardHeader)
+		return
+	}
+
+	name, command := c.Param("name"), c.Param("command")
+	if !dashboardCommands[command] {
+		c.String(http.StatusBadRequest, "Unknown command %s", command)
+		return
+	}
+	mc, ok := GetManager().LookupModule(name)
+	if !ok {
+		c.String(http.StatusNotFound, "Module %s not found", name)
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Error reading request")
+		return
+	}
+	content := strings.TrimSpace(string(body))
+	if command == "Start" {
+		content = name
+	}
+
+	res := runModuleCommand(&mc, command, content, c.GetHeader(com.TraceHeader))
+	log.Println("GO-WOXY Core - Dashboard command", command, "on", name, "by", c.GetString("user"), ":", res.Status, res.Code, res.Text())
+	c.String(apiStatus(res.Code), "%s", res.Text())
+}
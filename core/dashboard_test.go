@@ -0,0 +1,114 @@
+package core
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDashboard(t *testing.T) {
+	f, err := ioutil.TempFile("", "woxy-htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	sum := sha1.Sum([]byte("pass"))
+	f.WriteString("admin:{SHA}" + base64.StdEncoding.EncodeToString(sum[:]) + "\n")
+	f.Close()
+
+	c := &Config{
+		NAME:      "woxy-test",
+		LOG:       LogConfig{BUFFER: 100},
+		DASHBOARD: DashboardConfig{ENABLED: true, HTPASSWD: f.Name(), PATH: "/admin"},
+		MODULES: map[string]ModuleConfig{
+			"b": {NAME: "b", TYPES: "web", STATE: Online, DEPENDS_ON: []Dependency{{NAME: "a"}}},
+			"a": {NAME: "a", TYPES: "web", STATE: Stopped},
+		},
+	}
+	if err := c.checkDependencies(); err != nil {
+		t.Fatal(err)
+	}
+	GetManager().SetState(c)
+	cp := CommandProcessorImpl{}
+	cp.Init()
+	GetManager().SetCommandProcessor(&cp)
+
+	router := gin.New()
+	router.LoadHTMLGlob("../ressources/*/*")
+	c.serveDashboard(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   string
+		header bool
+		status int
+		want   string
+	}{
+		{"no credentials", http.MethodGet, "/admin", "", false, http.StatusUnauthorized, ""},
+		{"wrong password", http.MethodGet, "/admin", "admin:nope", false, http.StatusUnauthorized, ""},
+		{"unknown user", http.MethodGet, "/admin", "root:pass", false, http.StatusUnauthorized, ""},
+		{"page", http.MethodGet, "/admin", "admin:pass", false, http.StatusOK, `<body data-path="/admin" data-header="X-Woxy-Dashboard">`},
+		{"page title", http.MethodGet, "/admin", "admin:pass", false, http.StatusOK, "<title>woxy-test - go-woxy</title>"},
+		{"modules without credentials", http.MethodGet, "/admin/modules", "", false, http.StatusUnauthorized, ""},
+		{"command without credentials", http.MethodPost, "/admin/modules/b/Health", "", true, http.StatusUnauthorized, ""},
+		{"command without header", http.MethodPost, "/admin/modules/b/Health", "admin:pass", false, http.StatusForbidden, ""},
+		{"command not allowed", http.MethodPost, "/admin/modules/b/Shutdown", "admin:pass", true, http.StatusBadRequest, ""},
+		{"unknown module", http.MethodPost, "/admin/modules/x/Health", "admin:pass", true, http.StatusNotFound, ""},
+		{"command", http.MethodPost, "/admin/modules/b/Health", "admin:pass", true, http.StatusOK, `"LIVE":false`},
+		{"command conflict", http.MethodPost, "/admin/modules/b/Start", "admin:pass", true, http.StatusConflict, "Module already ONLINE"},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+		if tt.user != "" {
+			u := strings.SplitN(tt.user, ":", 2)
+			r.SetBasicAuth(u[0], u[1])
+		}
+		if tt.header {
+			r.Header.Set(dashboardHeader, "1")
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s : status = %d, want %d (%s)", tt.name, resp.StatusCode, tt.status, b)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s : no WWW-Authenticate header", tt.name)
+		}
+		if !strings.Contains(string(b), tt.want) {
+			t.Errorf("%s : body %q does not contain %q", tt.name, b, tt.want)
+		}
+	}
+
+	//MODULES ARE LISTED IN START ORDER
+	r, _ := http.NewRequest(http.MethodGet, srv.URL+"/admin/modules", nil)
+	r.SetBasicAuth("admin", "pass")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var mods []moduleSummary
+	if err := json.NewDecoder(resp.Body).Decode(&mods); err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 2 || mods[0].NAME != "a" || mods[1].NAME != "b" || mods[1].STATE != Online {
+		t.Errorf("dashboard modules = %+v, want a then b online", mods)
+	}
+}
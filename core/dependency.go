@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Dependency conditions
+const (
+	ConditionOnline  = "online"
+	ConditionReady   = "ready"
+	ConditionStarted = "started"
+)
+
+//defaultDependencyTimeout - Time given to a dependency to meet its condition when none is configured
+const defaultDependencyTimeout = 5 * time.Minute
+
+//dependencyPoll - Interval between two checks of dependency state
+var dependencyPoll = time.Second
+
+//Dependency - Module required by another one before it starts
+type Dependency struct {
+	CONDITION string
+	NAME      string
+	TIMEOUT   time.Duration
+}
+
+//UnmarshalYAML - Read Dependency from module name or from map with name and condition
+func (d *Dependency) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		d.NAME = name
+		return nil
+	}
+
+	type plain Dependency
+	return unmarshal((*plain)(d))
+}
+
+//met - Check if dependency module state satisfies condition, started only needs a live process while module is Loading
+func (d Dependency) met() bool {
+	m, ok := GetManager().LookupModule(d.NAME)
+	if !ok {
+		return false
+	}
+
+	switch d.CONDITION {
+	case ConditionReady:
+		return m.STATE.running() && m.ready()
+	case ConditionStarted:
+		if m.STATE.running() {
+			return true
+		}
+		p := GetManager().GetProcess(d.NAME)
+		return m.STATE == Loading && p != nil && p.running()
+	default:
+		return m.STATE.running()
+	}
+}
+
+//timeout - Get time given to dependency to meet its condition
+func (d Dependency) timeout() time.Duration {
+	if d.TIMEOUT > 0 {
+		return d.TIMEOUT
+	}
+	return defaultDependencyTimeout
+}
+
+//checkDependencies - Check module dependencies and compute start order, failing on unknown module or cycle
+func (c *Config) checkDependencies() error {
+	for k := range c.MODULES {
+		m := c.MODULES[k]
+		for i := range m.DEPENDS_ON {
+			d := &m.DEPENDS_ON[i]
+			if _, ok := c.MODULES[d.NAME]; !ok {
+				return errors.New("module " + k + " depends on unknown module " + d.NAME)
+			}
+			if d.CONDITION == "" {
+				d.CONDITION = ConditionOnline
+			} else if d.CONDITION != ConditionOnline && d.CONDITION != ConditionReady && d.CONDITION != ConditionStarted {
+				return errors.New("module " + k + " has unknown condition " + d.CONDITION + " on " + d.NAME)
+			}
+		}
+		c.MODULES[k] = m
+	}
+
+	//KAHN ALGORITHM, READY MODULES TAKEN IN NAME ORDER TO KEEP START ORDER STABLE
+	pending := map[string]int{}
+	dependents := map[string][]string{}
+	for k, m := range c.MODULES {
+		pending[k] = len(m.DEPENDS_ON)
+		for _, d := range m.DEPENDS_ON {
+			dependents[d.NAME] = append(dependents[d.NAME], k)
+		}
+	}
+
+	var ready, order []string
+	for k, n := range pending {
+		if n == 0 {
+			ready = append(ready, k)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		k := ready[0]
+		ready = ready[1:]
+		order = append(order, k)
+		for _, d := range dependents[k] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(order) != len(c.MODULES) {
+		var cycle []string
+		for k, n := range pending {
+			if n > 0 {
+				cycle = append(cycle, k)
+			}
+		}
+		sort.Strings(cycle)
+		return errors.New("dependency cycle between modules " + strings.Join(cycle, ", "))
+	}
+
+	c.order = order
+	return nil
+}
+
+//failed - Check if dependency module ended in a state it will not leave without an operator
+func (d Dependency) failed() bool {
+	m, ok := GetManager().LookupModule(d.NAME)
+	return ok && (m.STATE == Error || m.STATE == Failed || m.STATE == Stopped)
+}
+
+//waitDependencies - Hold module in Loading until all its dependencies are met, failing once one of them failed, stopped or timed out
+func (mc *ModuleConfig) waitDependencies() error {
+	for _, d := range mc.DEPENDS_ON {
+		if !d.met() {
+			log.Println("GO-WOXY Core - Mod", mc.NAME, "waiting for", d.NAME, d.CONDITION)
+		}
+		deadline := time.Now().Add(d.timeout())
+		for !d.met() {
+			if d.failed() {
+				return errors.New("dependency " + d.NAME + " failed")
+			}
+			if time.Now().After(deadline) {
+				return errors.New("dependency " + d.NAME + " not " + d.CONDITION + " after " + d.timeout().String())
+			}
+			time.Sleep(dependencyPoll)
+		}
+	}
+	return nil
+}
+
+//stopModules - Stop running modules, dependents before their dependencies
+func (c *Config) stopModules() {
+	names := c.moduleNames()
+	for i := len(names) - 1; i >= 0; i-- {
+		mc := GetManager().GetModule(names[i])
+		if !mc.STATE.running() || mc.EXE.REMOTE || strings.Contains(mc.TYPES, "bind") {
+			continue
+		}
+
+		log.Println("GO-WOXY Core - Stopping mod", mc.NAME)
+		if err := mc.Stop(context.Background()); err != nil {
+			log.Println("GO-WOXY Core - Error stopping mod", mc.NAME, ":", err)
+		}
+		GetManager().SaveModuleChanges(&mc)
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDependencies(t *testing.T) {
+	dep := func(names ...string) []Dependency {
+		var l []Dependency
+		for _, n := range names {
+			l = append(l, Dependency{NAME: n})
+		}
+		return l
+	}
+
+	tests := []struct {
+		name    string
+		deps    map[string][]Dependency
+		order   string
+		wantErr string
+	}{
+		{"no dependency", map[string][]Dependency{"b": nil, "a": nil, "c": nil}, "a,b,c", ""},
+		{"chain", map[string][]Dependency{"a": dep("b"), "b": dep("c"), "c": nil}, "c,b,a", ""},
+		{"diamond", map[string][]Dependency{"d": dep("b", "c"), "b": dep("a"), "c": dep("a"), "a": nil}, "a,b,c,d", ""},
+		{"independent kept in name order", map[string][]Dependency{"z": nil, "y": dep("x"), "x": nil}, "x,y,z", ""},
+		{"cycle", map[string][]Dependency{"a": dep("b"), "b": dep("c"), "c": dep("a"), "d": nil}, "", "dependency cycle between modules a, b, c"},
+		{"self dependency", map[string][]Dependency{"a": dep("a")}, "", "dependency cycle between modules a"},
+		{"unknown module", map[string][]Dependency{"a": dep("x")}, "", "depends on unknown module x"},
+		{"unknown condition", map[string][]Dependency{"a": {{NAME: "b", CONDITION: "healthy"}}, "b": nil}, "", "unknown condition healthy"},
+	}
+	for _, tt := range tests {
+		c := &Config{MODULES: map[string]ModuleConfig{}}
+		for n, d := range tt.deps {
+			c.MODULES[n] = ModuleConfig{NAME: n, DEPENDS_ON: d}
+		}
+		err := c.checkDependencies()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s : error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s : unexpected error %v", tt.name, err)
+		} else if got := strings.Join(c.order, ","); got != tt.order {
+			t.Errorf("%s : order = %s, want %s", tt.name, got, tt.order)
+		}
+	}
+}
+
+func TestWaitDependencies(t *testing.T) {
+	poll := dependencyPoll
+	dependencyPoll = 10 * time.Millisecond
+	defer func() { dependencyPoll = poll }()
+
+	tests := []struct {
+		state     ModuleState
+		condition string
+		process   bool
+		met       bool
+		wantErr   string
+	}{
+		{Online, ConditionOnline, false, true, ""},
+		{Online, ConditionStarted, false, true, ""},
+		{LimitExceeded, ConditionOnline, false, true, ""},
+		{Loading, ConditionStarted, true, true, ""},
+		{Loading, ConditionOnline, true, false, "not online after"},
+		{Loading, ConditionStarted, false, false, "not started after"},
+		{Downloaded, ConditionOnline, false, false, "not online after"},
+		{Unknown, ConditionOnline, false, false, "not online after"},
+		{Stopped, ConditionOnline, false, false, "dependency dep failed"},
+		{Error, ConditionOnline, false, false, "dependency dep failed"},
+		{Failed, ConditionStarted, false, false, "dependency dep failed"},
+	}
+	for _, tt := range tests {
+		GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{"dep": {NAME: "dep", STATE: tt.state}}})
+		p := &moduleProcess{done: make(chan struct{}), process: &os.Process{Pid: os.Getpid()}}
+		if tt.process {
+			GetManager().SetProcess("dep", p)
+		}
+		d := Dependency{NAME: "dep", CONDITION: tt.condition, TIMEOUT: 50 * time.Millisecond}
+		if got := d.met(); got != tt.met {
+			t.Errorf("%s %s : met = %v, want %v", tt.state, tt.condition, got, tt.met)
+		}
+		mc := ModuleConfig{NAME: "m", DEPENDS_ON: []Dependency{d}}
+		err := mc.waitDependencies()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s %s : unexpected error %v", tt.state, tt.condition, err)
+		} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s %s : waitDependencies error = %v, want %q", tt.state, tt.condition, err, tt.wantErr)
+		}
+		GetManager().RemoveProcess("dep", p)
+	}
+}
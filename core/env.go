@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+//SecretMap - Module secrets, values are masked when printed or encoded
+type SecretMap map[string]string
+
+//String - Print SecretMap with masked values
+func (s SecretMap) String() string {
+	var keys []string
+	for k := range s {
+		keys = append(keys, k+":****")
+	}
+	sort.Strings(keys)
+	return "map[" + strings.Join(keys, " ") + "]"
+}
+
+//MarshalJSON - Encode SecretMap with masked values
+func (s SecretMap) MarshalJSON() ([]byte, error) {
+	m := map[string]string{}
+	for k := range s {
+		m[k] = "****"
+	}
+	return json.Marshal(m)
+}
+
+//interpolate - Replace ${VAR} with core environment variable and ${file:path} with file content, any other $ is kept as is
+func interpolate(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+2:], '}')
+		if j <= 0 {
+			//UNCLOSED OR EMPTY ${} STAYS LITERAL
+			b.WriteString(s[:i+2])
+			s = s[i+2:]
+			continue
+		}
+
+		v, err := expand(s[i+2 : i+2+j])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i])
+		b.WriteString(v)
+		s = s[i+3+j:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+//expand - Get value of interpolated name, file content or environment variable
+func expand(name string) (string, error) {
+	if strings.HasPrefix(name, "file:") {
+		b, err := ioutil.ReadFile(strings.TrimPrefix(name, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", errors.New("environment variable " + name + " not set")
+	}
+	return v, nil
+}
+
+//environment - Get module process environment with interpolated env and secrets, and secret values to mask
+func (mc *ModuleConfig) environment() ([]string, []string, error) {
+	env := mc.baseEnvironment()
+	for _, k := range sortedKeys(mc.EXE.ENV) {
+		v, err := interpolate(mc.EXE.ENV[k])
+		if err != nil {
+			return nil, nil, errors.New("env " + k + " : " + err.Error())
+		}
+		env = append(env, k+"="+v)
+	}
+
+	var masks []string
+	for _, k := range sortedKeys(mc.EXE.SECRETS) {
+		v, err := interpolate(mc.EXE.SECRETS[k])
+		if err != nil {
+			return nil, nil, errors.New("secret " + k + " : " + err.Error())
+		}
+		env = append(env, k+"="+v)
+		if v != "" {
+			masks = append(masks, v)
+		}
+	}
+	return env, masks, nil
+}
+
+//arguments - Get module process arguments interpolated
+func (mc *ModuleConfig) arguments() ([]string, error) {
+	var args []string
+	for _, a := range mc.EXE.ARGS {
+		v, err := interpolate(a)
+		if err != nil {
+			return nil, errors.New("args : " + err.Error())
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("WOXY_TEST_HOST", "example.org")
+	f, err := ioutil.TempFile("", "woxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("s3cr3t\n")
+	f.Close()
+
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{"plain", "plain", false},
+		{"https://${WOXY_TEST_HOST}/v1", "https://example.org/v1", false},
+		{"${WOXY_TEST_HOST}${WOXY_TEST_HOST}", "example.orgexample.org", false},
+		{"${file:" + f.Name() + "}", "s3cr3t", false},
+		{"pa$$word", "pa$$word", false},
+		{"$WOXY_TEST_HOST $1 $", "$WOXY_TEST_HOST $1 $", false},
+		{"$${WOXY_TEST_HOST}", "$example.org", false},
+		{"${} and ${unclosed", "${} and ${unclosed", false},
+		{"${WOXY_TEST_UNSET}", "", true},
+		{"${file:/nonexistent/woxy}", "", true},
+	}
+	for _, tt := range tests {
+		got, err := interpolate(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("interpolate(%q) = %q, %v, want %q, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
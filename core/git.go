@@ -0,0 +1,283 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//maxPreviousCommits - Number of deployed commits kept for rollback
+const maxPreviousCommits = 10
+
+//gitVersionOnce - Installed git version is checked once, result kept in gitVersionErr
+var (
+	gitVersionOnce sync.Once
+	gitVersionErr  error
+)
+
+//GitAuthConfig - Credentials used by git to fetch private module repositories
+type GitAuthConfig struct {
+	KNOWN_HOSTS string
+	SSH_KEY     string
+	TOKEN       string
+	TOKEN_ENV   string
+	TOKEN_FILE  string
+	USERNAME    string
+}
+
+//String - Print GitAuthConfig without inline token
+func (g GitAuthConfig) String() string {
+	token := ""
+	if g.TOKEN != "" {
+		token = "****"
+	}
+	return "{" + g.KNOWN_HOSTS + " " + g.SSH_KEY + " " + token + " " + g.TOKEN_ENV + " " + g.TOKEN_FILE + " " + g.USERNAME + "}"
+}
+
+//MarshalJSON - Encode GitAuthConfig without inline token
+func (g GitAuthConfig) MarshalJSON() ([]byte, error) {
+	type plain GitAuthConfig
+	if g.TOKEN != "" {
+		g.TOKEN = "****"
+	}
+	return json.Marshal(plain(g))
+}
+
+//merge - Get GitAuthConfig with empty fields taken from global configuration
+func (g GitAuthConfig) merge(global GitAuthConfig) GitAuthConfig {
+	if g.KNOWN_HOSTS == "" {
+		g.KNOWN_HOSTS = global.KNOWN_HOSTS
+	}
+	if g.SSH_KEY == "" {
+		g.SSH_KEY = global.SSH_KEY
+	}
+	if g.TOKEN == "" && g.TOKEN_ENV == "" && g.TOKEN_FILE == "" {
+		g.TOKEN, g.TOKEN_ENV, g.TOKEN_FILE = global.TOKEN, global.TOKEN_ENV, global.TOKEN_FILE
+	}
+	if g.USERNAME == "" {
+		g.USERNAME = global.USERNAME
+	}
+	return g
+}
+
+//token - Get HTTPS token from config, environment variable or file
+func (g GitAuthConfig) token() (string, error) {
+	switch {
+	case g.TOKEN != "":
+		return g.TOKEN, nil
+	case g.TOKEN_ENV != "":
+		t := os.Getenv(g.TOKEN_ENV)
+		if t == "" {
+			return "", errors.New("git token environment variable " + g.TOKEN_ENV + " is empty")
+		}
+		return t, nil
+	case g.TOKEN_FILE != "":
+		b, err := ioutil.ReadFile(g.TOKEN_FILE)
+		if err != nil {
+			return "", errors.New("reading git token file " + g.TOKEN_FILE + " : " + err.Error())
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return "", nil
+}
+
+//env - Get environment of git command with credentials, token is returned to be masked in outputs
+func (g GitAuthConfig) env() ([]string, string, error) {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if g.SSH_KEY != "" || g.KNOWN_HOSTS != "" {
+		ssh := "ssh"
+		if g.SSH_KEY != "" {
+			ssh += " -i " + shellQuote(g.SSH_KEY) + " -o IdentitiesOnly=yes"
+		}
+		if g.KNOWN_HOSTS != "" {
+			ssh += " -o UserKnownHostsFile=" + shellQuote(g.KNOWN_HOSTS) + " -o StrictHostKeyChecking=yes"
+		}
+		env = append(env, "GIT_SSH_COMMAND="+ssh)
+	}
+
+	token, err := g.token()
+	if err != nil || token == "" {
+		return env, "", err
+	}
+	if err := checkGitVersion(); err != nil {
+		return nil, "", err
+	}
+
+	user := g.USERNAME
+	if user == "" {
+		user = "git"
+	}
+
+	//PASS HEADER THROUGH ENVIRONMENT SO TOKEN NEVER APPEARS IN PROCESS ARGUMENTS
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+token))
+	env = append(env, "GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0="+header)
+	return env, token, nil
+}
+
+//checkGitVersion - Check installed git reads GIT_CONFIG_COUNT (2.31+), older ones would fetch without token
+func checkGitVersion() error {
+	gitVersionOnce.Do(func() {
+		out, err := exec.Command("git", "version").Output()
+		if err != nil {
+			gitVersionErr = errors.New("git version : " + err.Error())
+			return
+		}
+		major, minor, ok := parseGitVersion(string(out))
+		if !ok {
+			gitVersionErr = errors.New("unknown git version : " + strings.TrimSpace(string(out)))
+		} else if major < 2 || major == 2 && minor < 31 {
+			gitVersionErr = errors.New("git 2.31 or later needed for token authentication, found " + strings.TrimSpace(string(out)))
+		}
+	})
+	return gitVersionErr
+}
+
+//parseGitVersion - Get major and minor version from "git version" output
+func parseGitVersion(out string) (int, int, bool) {
+	f := strings.Fields(out)
+	if len(f) < 3 || f[0] != "git" || f[1] != "version" {
+		return 0, 0, false
+	}
+	v := strings.SplitN(f[2], ".", 3)
+	if len(v) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(v[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(v[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+//shellQuote - Quote value for sh, as GIT_SSH_COMMAND is run by a shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+//gitAuth - Get module git credentials merged with global ones
+func (mc *ModuleConfig) gitAuth() GitAuthConfig {
+	if c := GetManager().GetConfig(); c != nil {
+		return mc.EXE.GIT.merge(c.GIT)
+	}
+	return mc.EXE.GIT
+}
+
+//downloadGit - Clone or fetch module repository and checkout configured ref
+func (mc *ModuleConfig) downloadGit(ctx context.Context) error {
+	action := "Update"
+
+	wd := "./mods/"
+	if _, err := os.Stat(wd + mc.NAME + "/"); os.IsNotExist(err) {
+		action = "Downloaded"
+		if _, err := mc.git(ctx, wd, "clone", "--no-checkout", mc.EXE.SRC, mc.NAME); err != nil {
+			return err
+		}
+	}
+
+	mc.EXE.BIN = "./mods/" + mc.NAME + "/"
+
+	//ROLLED BACK MODULE STAYS ON PINNED COMMIT UNTIL NEXT DEPLOY
+	commit := mc.PINNED_COMMIT
+	if commit == "" {
+		var err error
+		if commit, err = mc.resolveRef(ctx); err != nil {
+			return err
+		}
+	}
+	if err := mc.checkout(ctx, commit); err != nil {
+		return err
+	}
+	if mc.PINNED_COMMIT != "" {
+		fmt.Println(action, " mod : ", mc.NAME, " - pinned at ", commit)
+		return nil
+	}
+	fmt.Println(action, " mod : ", mc.NAME, " - ref ", mc.EXE.REF, " at ", commit)
+	return nil
+}
+
+//git - Run git command in module directory
+func (mc *ModuleConfig) git(ctx context.Context, dir string, args ...string) (string, error) {
+	env, token, err := mc.gitAuth().env()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	b, err := cmd.CombinedOutput()
+
+	out := string(b)
+	if token != "" {
+		out = strings.ReplaceAll(out, token, "****")
+	}
+	if err != nil {
+		return out, errors.New("git " + args[0] + " : " + err.Error() + " - " + strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+//resolveRef - Fetch module repository and resolve EXE.REF (branch, tag or commit) to a commit SHA
+func (mc *ModuleConfig) resolveRef(ctx context.Context) (string, error) {
+	dir := mc.EXE.BIN
+	if _, err := mc.git(ctx, dir, "fetch", "--force", "--tags", "origin"); err != nil {
+		return "", err
+	}
+
+	ref := mc.EXE.REF
+	var candidates []string
+	if ref == "" {
+		//NO REF -> DEFAULT BRANCH OF REMOTE
+		candidates = []string{"origin/HEAD"}
+	} else {
+		//BRANCH FIRST SO A MOVED BRANCH IS FOLLOWED, THEN TAG, THEN COMMIT
+		candidates = []string{"refs/remotes/origin/" + ref, "refs/tags/" + ref, ref}
+	}
+
+	for _, c := range candidates {
+		if out, err := mc.git(ctx, dir, "rev-parse", "--verify", "--quiet", c+"^{commit}"); err == nil {
+			return strings.TrimSpace(out), nil
+		}
+	}
+
+	//COMMIT NOT REACHABLE FROM ANY BRANCH OR TAG
+	if ref != "" {
+		if _, err := mc.git(ctx, dir, "fetch", "origin", ref); err == nil {
+			if out, err := mc.git(ctx, dir, "rev-parse", "--verify", "--quiet", "FETCH_HEAD^{commit}"); err == nil {
+				return strings.TrimSpace(out), nil
+			}
+		}
+	}
+	return "", errors.New("ref " + ref + " not found in " + mc.NAME + " repository")
+}
+
+//checkout - Checkout commit in module directory and record it as deployed commit
+func (mc *ModuleConfig) checkout(ctx context.Context, commit string) error {
+	if _, err := mc.git(ctx, mc.EXE.BIN, "checkout", "--force", "--detach", commit); err != nil {
+		return err
+	}
+
+	if mc.COMMIT != commit {
+		if mc.COMMIT != "" {
+			mc.PREVIOUS_COMMITS = append(mc.PREVIOUS_COMMITS, mc.COMMIT)
+			if len(mc.PREVIOUS_COMMITS) > maxPreviousCommits {
+				mc.PREVIOUS_COMMITS = mc.PREVIOUS_COMMITS[len(mc.PREVIOUS_COMMITS)-maxPreviousCommits:]
+			}
+		}
+		mc.COMMIT = commit
+	}
+	return nil
+}
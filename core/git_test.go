@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		out   string
+		major int
+		minor int
+		ok    bool
+	}{
+		{"git version 2.39.5\n", 2, 39, true},
+		{"git version 2.31.0.windows.1", 2, 31, true},
+		{"git version 2.30.2 (Apple Git-128)", 2, 30, true},
+		{"git version 1.8.3.1", 1, 8, true},
+		{"git version 3", 0, 0, false},
+		{"git version x.y", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		major, minor, ok := parseGitVersion(tt.out)
+		if major != tt.major || minor != tt.minor || ok != tt.ok {
+			t.Errorf("parseGitVersion(%q) = %d, %d, %v, want %d, %d, %v", tt.out, major, minor, ok, tt.major, tt.minor, tt.ok)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []string{
+		"/root/.ssh/id_ed25519",
+		"/home/o'brien/.ssh/key",
+		"/keys/a b/$HOME/`id`",
+		"'",
+	}
+	for _, s := range tests {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != s {
+			t.Errorf("shellQuote(%q) read by sh as %q", s, out)
+		}
+	}
+}
+
+func TestGitAuthEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		auth GitAuthConfig
+		want []string
+	}{
+		{"ssh key", GitAuthConfig{SSH_KEY: "/k'ey"}, []string{`GIT_SSH_COMMAND=ssh -i '/k'\''ey' -o IdentitiesOnly=yes`}},
+		{"known hosts", GitAuthConfig{KNOWN_HOSTS: "/kh"}, []string{"GIT_SSH_COMMAND=ssh -o UserKnownHostsFile='/kh' -o StrictHostKeyChecking=yes"}},
+		{"token", GitAuthConfig{TOKEN: "t"}, []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=http.extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic Z2l0OnQ="}},
+	}
+	for _, tt := range tests {
+		env, _, err := tt.auth.env()
+		if err != nil {
+			t.Fatalf("%s : %v", tt.name, err)
+		}
+		all := strings.Join(env, "\n")
+		for _, w := range tt.want {
+			if !strings.Contains(all, "\n"+w+"\n") && !strings.HasSuffix(all, "\n"+w) {
+				t.Errorf("%s : %q missing from environment", tt.name, w)
+			}
+		}
+	}
+}
+
+//gitTestRepo - Bare repository with its work clone, commit writes version in main.go and pushes ref
+type gitTestRepo struct {
+	t    *testing.T
+	bare string
+	work string
+}
+
+func (r gitTestRepo) run(args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=woxy", "-c", "user.email=woxy@localhost"}, args...)...)
+	cmd.Dir = r.work
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v : %v - %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r gitTestRepo) commit(version string, ref string) string {
+	ioutil.WriteFile(filepath.Join(r.work, "main.go"), []byte("package main\n\n//"+version+"\nfunc main() {}\n"), 0644)
+	r.run("add", "main.go")
+	r.run("commit", "-q", "-m", version)
+	r.run("push", "-q", "--force", "origin", "HEAD:"+ref)
+	return r.run("rev-parse", "HEAD")
+}
+
+func TestGitRefAndRollback(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := ioutil.TempDir("", "woxy-git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+	os.Mkdir("mods", 0755)
+
+	r := gitTestRepo{t: t, bare: filepath.Join(dir, "origin.git"), work: dir}
+	r.run("init", "-q", "--bare", r.bare)
+	r.work = filepath.Join(dir, "work")
+	os.Mkdir(r.work, 0755)
+	r.run("init", "-q")
+	r.run("remote", "add", "origin", r.bare)
+	c1 := r.commit("v1", "refs/heads/main")
+	r.run("tag", "v1")
+	r.run("push", "-q", "origin", "v1")
+	c2 := r.commit("v2", "refs/heads/main")
+	c3 := r.commit("feature", "refs/heads/feature")
+	r.run("reset", "-q", "--hard", c2)
+	exec.Command("git", "--git-dir", r.bare, "symbolic-ref", "HEAD", "refs/heads/main").Run()
+
+	src := "file://" + r.bare
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{"m": {NAME: "m", EXE: ModuleExecConfig{SRC: src}}}})
+	mc := GetManager().GetModule("m")
+	ctx := context.Background()
+	if err := mc.downloadGit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mc.COMMIT != c2 || len(mc.PREVIOUS_COMMITS) != 0 {
+		t.Fatalf("clone without ref at %s, previous %v, want %s", mc.COMMIT, mc.PREVIOUS_COMMITS, c2)
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"", c2, false},
+		{"main", c2, false},
+		{"feature", c3, false},
+		{"v1", c1, false},
+		{c1, c1, false},
+		{c3[:10], c3, false},
+		{"missing", "", true},
+	}
+	for _, tt := range tests {
+		m := mc
+		m.EXE.REF = tt.ref
+		got, err := m.resolveRef(ctx)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveRef(%q) = %s, %v, want %s", tt.ref, got, err, tt.want)
+		}
+	}
+
+	//BRANCH MOVED ON REMOTE IS FOLLOWED, DEPLOYED COMMIT GOES TO PREVIOUS COMMITS
+	mc.EXE.REF = "main"
+	c4 := r.commit("v4", "refs/heads/main")
+	if err := mc.downloadGit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mc.COMMIT != c4 || strings.Join(mc.PREVIOUS_COMMITS, ",") != c2 {
+		t.Fatalf("update at %s, previous %v, want %s, [%s]", mc.COMMIT, mc.PREVIOUS_COMMITS, c4, c2)
+	}
+
+	//ROLLBACK PINS PREVIOUS COMMIT, DOWNLOAD STAYS ON IT WHILE REF MOVES ON
+	mc.PINNED_COMMIT, mc.COMMIT, mc.PREVIOUS_COMMITS = c2, c2, nil
+	r.commit("v5", "refs/heads/main")
+	if err := mc.downloadGit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := mc.git(ctx, mc.EXE.BIN, "rev-parse", "HEAD")
+	b, _ := ioutil.ReadFile(filepath.Join(mc.EXE.BIN, "main.go"))
+	if strings.TrimSpace(head) != c2 || mc.COMMIT != c2 || len(mc.PREVIOUS_COMMITS) != 0 || !strings.Contains(string(b), "//v2") {
+		t.Errorf("pinned download at %s (commit %s, previous %v), want %s", head, mc.COMMIT, mc.PREVIOUS_COMMITS, c2)
+	}
+
+	//WEBHOOK PUSH SKIPS PINNED MODULE
+	GetManager().SaveModuleChanges(&mc)
+	var p pushPayload
+	p.Repository.CloneURL = src
+	mods, pinned := p.matchModules("main")
+	if len(mods) != 0 || strings.Join(pinned, ",") != "m" {
+		t.Errorf("push on pinned module matched %v, pinned %v", mods, pinned)
+	}
+	mc.PINNED_COMMIT = ""
+	GetManager().SaveModuleChanges(&mc)
+	if mods, pinned = p.matchModules("main"); strings.Join(mods, ",") != "m" || len(pinned) != 0 {
+		t.Errorf("push on module matched %v, pinned %v", mods, pinned)
+	}
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Probe types
+const (
+	ProbeCommand = "command"
+	ProbeHTTP    = "http"
+	ProbeTCP     = "tcp"
+)
+
+//Restart policies applied on liveness failure
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+)
+
+//HealthConfig - Module liveness and readiness probes
+type HealthConfig struct {
+	LIVENESS     ProbeConfig
+	MAX_RESTARTS int
+	READINESS    ProbeConfig
+	RESTART      string
+}
+
+//ProbeConfig - Probe checking module by HTTP request, TCP connection or command
+type ProbeConfig struct {
+	BODY              string
+	COMMAND           []string
+	FAILURE_THRESHOLD int
+	INITIAL_DELAY     time.Duration
+	INTERVAL          time.Duration
+	PATH              string
+	STATUS            int
+	SUCCESS_THRESHOLD int
+	TIMEOUT           time.Duration
+	TYPE              string
+}
+
+//HealthStatus - Last result of module probes
+type HealthStatus struct {
+	LAST_ERROR string
+	LAST_PROBE time.Time
+	LIVE       bool
+	READY      bool
+	RESTARTS   int
+}
+
+func (p ProbeConfig) enabled() bool {
+	return p.TYPE != ""
+}
+
+//ready - Check module readiness, always ready without readiness probe
+func (mc *ModuleConfig) ready() bool {
+	return !mc.HEALTH.READINESS.enabled() || GetManager().GetHealth(mc.NAME).READY
+}
+
+//check - Check probe type and set default thresholds and intervals
+func (p *ProbeConfig) check() error {
+	switch p.TYPE {
+	case "":
+		return nil
+	case ProbeHTTP, ProbeTCP:
+	case ProbeCommand:
+		if len(p.COMMAND) == 0 {
+			return errors.New("command probe without command")
+		}
+	default:
+		return errors.New("unknown probe type " + p.TYPE)
+	}
+
+	if p.INTERVAL <= 0 {
+		p.INTERVAL = 10 * time.Second
+	}
+	if p.TIMEOUT <= 0 {
+		p.TIMEOUT = 2 * time.Second
+	}
+	if p.FAILURE_THRESHOLD <= 0 {
+		p.FAILURE_THRESHOLD = 3
+	}
+	if p.SUCCESS_THRESHOLD <= 0 {
+		p.SUCCESS_THRESHOLD = 1
+	}
+	return nil
+}
+
+//check - Check probes and restart policy
+func (h *HealthConfig) check() error {
+	if err := h.LIVENESS.check(); err != nil {
+		return errors.New("liveness : " + err.Error())
+	}
+	if err := h.READINESS.check(); err != nil {
+		return errors.New("readiness : " + err.Error())
+	}
+	if h.RESTART == "" {
+		h.RESTART = RestartNever
+	} else if h.RESTART != RestartNever && h.RESTART != RestartOnFailure {
+		return errors.New("unknown restart policy " + h.RESTART)
+	}
+	return nil
+}
+
+//probe - Run probe once against module
+func (mc *ModuleConfig) probe(p ProbeConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.TIMEOUT)
+	defer cancel()
+
+	addr := net.JoinHostPort(mc.BINDING.ADDRESS, mc.port())
+	switch p.TYPE {
+	case ProbeTCP:
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ProbeCommand:
+		cmd := exec.CommandContext(ctx, p.COMMAND[0], p.COMMAND[1:]...)
+		cmd.Dir = mc.EXE.BIN
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return errors.New(err.Error() + " : " + strings.TrimSpace(string(out)))
+		}
+		return nil
+
+	default:
+		req, err := http.NewRequest("GET", mc.BINDING.PROTOCOL+"://"+addr+p.PATH, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		if p.STATUS != 0 && resp.StatusCode != p.STATUS {
+			return errors.New("status " + strconv.Itoa(resp.StatusCode) + " instead of " + strconv.Itoa(p.STATUS))
+		} else if p.STATUS == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+			return errors.New("status " + strconv.Itoa(resp.StatusCode))
+		}
+		if p.BODY != "" && !strings.Contains(string(body), p.BODY) {
+			return errors.New("body does not contain " + p.BODY)
+		}
+		return nil
+	}
+}
+
+//watchHealth - Run module probes until its process exits
+func (mc *ModuleConfig) watchHealth(p *moduleProcess) {
+	GetManager().StopHealthWatch(mc.NAME)
+	mc.runProbes(p.done, nil, p)
+}
+
+//watchExternalHealth - Run probes of module not started by core, until it is set up again or stopped
+func (mc *ModuleConfig) watchExternalHealth() {
+	if !mc.HEALTH.READINESS.enabled() && !mc.HEALTH.LIVENESS.enabled() {
+		return
+	}
+	hw := GetManager().WatchHealth(mc.NAME)
+	mc.runProbes(hw.done, &hw.wg, nil)
+}
+
+//canProbe - Check probe can run, command probes need module directory on core host
+func (mc *ModuleConfig) canProbe(pc ProbeConfig) bool {
+	return pc.TYPE != ProbeCommand || !mc.EXE.REMOTE
+}
+
+//runProbes - Start module probe loops until done is closed, counted in wg when set, p is nil for module not started by core
+func (mc *ModuleConfig) runProbes(done <-chan struct{}, wg *sync.WaitGroup, p *moduleProcess) {
+	start := func(probe func()) {
+		if wg != nil {
+			wg.Add(1)
+		}
+		go func() {
+			probe()
+			if wg != nil {
+				wg.Done()
+			}
+		}()
+	}
+
+	h := mc.HEALTH
+	if h.READINESS.enabled() && !mc.canProbe(h.READINESS) {
+		log.Println("GO-WOXY Core - Readiness probe of remote mod", mc.NAME, "ignored : command probes only run on modules of core host")
+		h.READINESS = ProbeConfig{}
+	}
+	if h.LIVENESS.enabled() && !mc.canProbe(h.LIVENESS) {
+		log.Println("GO-WOXY Core - Liveness probe of remote mod", mc.NAME, "ignored : command probes only run on modules of core host")
+		h.LIVENESS = ProbeConfig{}
+	}
+	GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+		s.LIVE = true
+		s.READY = !h.READINESS.enabled()
+	})
+
+	if h.READINESS.enabled() {
+		start(func() {
+			mc.runProbe(done, "readiness", h.READINESS, func(ok bool, err error) {
+				GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+					s.READY = ok
+				})
+			})
+		})
+	}
+
+	if h.LIVENESS.enabled() {
+		start(func() {
+			mc.runProbe(done, "liveness", h.LIVENESS, func(ok bool, err error) {
+				GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+					s.LIVE = ok
+				})
+				if !ok {
+					mc.livenessFailed(p, err)
+				}
+			})
+		})
+	}
+}
+
+//runProbe - Run probe at its interval, calling update when result passes success or failure threshold
+func (mc *ModuleConfig) runProbe(done <-chan struct{}, name string, pc ProbeConfig, update func(bool, error)) {
+	select {
+	case <-done:
+		return
+	case <-time.After(pc.INITIAL_DELAY):
+	}
+
+	ticker := time.NewTicker(pc.INTERVAL)
+	defer ticker.Stop()
+
+	successes, failures := 0, 0
+	for {
+		//PROBE ONLY ONCE MODULE REGISTERED, IT MAY STILL BE INITIALIZING
+		if m := GetManager().GetModule(mc.NAME); m.STATE != Loading && m.STATE != Downloaded {
+			err := m.probe(pc)
+			GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+				s.LAST_PROBE = time.Now()
+				s.LAST_ERROR = ""
+				if err != nil {
+					s.LAST_ERROR = name + " : " + err.Error()
+				}
+			})
+
+			if err == nil {
+				successes, failures = successes+1, 0
+				if successes == pc.SUCCESS_THRESHOLD {
+					update(true, nil)
+				}
+			} else {
+				successes, failures = 0, failures+1
+				if failures == pc.FAILURE_THRESHOLD {
+					update(false, err)
+				}
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//livenessFailed - Apply module restart policy after liveness probe failed, only modules started by core are restarted
+func (mc *ModuleConfig) livenessFailed(p *moduleProcess, err error) {
+	log.Println("GO-WOXY Core - Mod", mc.NAME, "liveness probe failed :", err)
+	GetManager().GetLogStore(mc.NAME).Event("", "liveness probe failed : "+err.Error())
+
+	h := mc.HEALTH
+	restarts := GetManager().GetHealth(mc.NAME).RESTARTS
+	if h.RESTART != RestartOnFailure || p == nil || GetManager().GetProcess(mc.NAME) != p {
+		return
+	} else if h.MAX_RESTARTS > 0 && restarts >= h.MAX_RESTARTS {
+		log.Println("GO-WOXY Core - Mod", mc.NAME, "not restarted : max restarts reached")
+		return
+	}
+
+	GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+		s.RESTARTS++
+	})
+	m := GetManager().GetModule(mc.NAME)
+	log.Println("GO-WOXY Core - Restarting mod", m.NAME)
+	if err := m.Stop(context.Background()); err != nil {
+		log.Println("GO-WOXY Core - Error stopping mod", m.NAME, ":", err)
+	}
+	if err := m.Setup(context.Background(), GetManager().GetRouter(), false); err != nil {
+		log.Println("GO-WOXY Core - Error restarting mod", m.NAME, ":", err)
+	}
+	GetManager().SaveModuleChanges(&m)
+}
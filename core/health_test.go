@@ -0,0 +1,65 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExternalModuleReadiness(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	probe := func(typ string) ProbeConfig {
+		p := ProbeConfig{TYPE: typ, INTERVAL: 10 * time.Millisecond, FAILURE_THRESHOLD: 1, COMMAND: []string{"false"}}
+		p.check()
+		return p
+	}
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		remote bool
+		probe  ProbeConfig
+		ready  bool
+	}{
+		{"http up", up, true, probe(ProbeHTTP), true},
+		{"http down", down, true, probe(ProbeHTTP), false},
+		{"tcp up", up, false, probe(ProbeTCP), true},
+		{"remote command", down, true, probe(ProbeCommand), true},
+		{"local command", up, false, probe(ProbeCommand), false},
+	}
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{}})
+	defer GetManager().StopHealthWatch("ext")
+	for _, tt := range tests {
+		host, port, _ := net.SplitHostPort(tt.server.Listener.Addr().String())
+		mc := ModuleConfig{
+			NAME:    "ext",
+			BINDING: ServerConfig{ADDRESS: host, PORT: port, PROTOCOL: "http"},
+			EXE:     ModuleExecConfig{REMOTE: tt.remote},
+			HEALTH:  HealthConfig{READINESS: tt.probe},
+			STATE:   Online,
+		}
+		GetManager().SaveModuleChanges(&mc)
+		GetManager().SetHealth("ext", func(s *HealthStatus) { *s = HealthStatus{} })
+
+		mc.watchExternalHealth()
+		time.Sleep(100 * time.Millisecond)
+		if got := mc.ready(); got != tt.ready {
+			t.Errorf("%s : ready = %v, want %v (%s)", tt.name, got, tt.ready, GetManager().GetHealth("ext").LAST_ERROR)
+		}
+
+		//PROBES HAVE EXITED ONCE WATCH IS STOPPED
+		GetManager().StopHealthWatch("ext")
+		last := GetManager().GetHealth("ext").LAST_PROBE
+		time.Sleep(30 * time.Millisecond)
+		if !GetManager().GetHealth("ext").LAST_PROBE.Equal(last) {
+			t.Errorf("%s : probe still running after StopHealthWatch", tt.name)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Wariie/go-woxy/com"
+	"github.com/Wariie/go-woxy/tools"
+)
+
+//maxJobs - Number of jobs kept in memory
+const maxJobs = 100
+
+//maxJobWait - Longest wait for a job to finish asked by Job command
+const maxJobWait = time.Minute
+
+//JobConfig - Asynchronous commands configuration
+type JobConfig struct {
+	TIMEOUT time.Duration
+}
+
+//JobState - State of a Job
+type JobState string
+
+const (
+	JobPending   JobState = "PENDING"
+	JobRunning   JobState = "RUNNING"
+	JobSucceeded JobState = "SUCCEEDED"
+	JobFailed    JobState = "FAILED"
+	JobCanceled  JobState = "CANCELED"
+)
+
+//Job - Command run in background, until modules it started are online
+type Job struct {
+	COMMAND  string
+	CREATED  time.Time
+	FINISHED time.Time
+	ID       string
+	MODULES  []string
+	RESULT   *com.CommandResult `json:",omitempty"`
+	STARTED  time.Time
+	STATE    JobState
+	STEPS    []JobStep
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+//JobStep - Progress of a Job
+type JobStep struct {
+	MESSAGE string
+	TIME    time.Time
+}
+
+//finished - Check job is over
+func (j Job) finished() bool {
+	return j.STATE == JobSucceeded || j.STATE == JobFailed || j.STATE == JobCanceled
+}
+
+//hasModule - Check job runs command on module
+func (j Job) hasModule(name string) bool {
+	return contains(j.MODULES, name)
+}
+
+//step - Add progress step and save job
+func (j *Job) step(message string) {
+	j.STEPS = append(j.STEPS, JobStep{MESSAGE: message, TIME: time.Now()})
+	GetManager().SaveJob(*j)
+}
+
+//startJob - Run command on modules in background, answer is ACCEPTED result holding the job
+func startJob(command string, modules []string, run func(ctx context.Context, j *Job) com.CommandResult) com.CommandResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := Job{
+		COMMAND: command,
+		CREATED: time.Now(),
+		ID:      tools.String(12),
+		MODULES: modules,
+		STATE:   JobPending,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	GetManager().SaveJob(j)
+	go j.run(ctx, run)
+
+	rb, err := json.Marshal(j)
+	if err != nil {
+		return com.NewCommandResult(command, "", err, 0)
+	}
+	res := com.NewCommandResult(command, string(rb), nil, 0)
+	res.Code = com.CodeAccepted
+	return res
+}
+
+//run - Run job and save its result, it is canceled when its context is
+func (j Job) run(ctx context.Context, run func(ctx context.Context, j *Job) com.CommandResult) {
+	defer close(j.done)
+	defer j.cancel()
+
+	j.STARTED = time.Now()
+	j.STATE = JobRunning
+	GetManager().SaveJob(j)
+
+	res := run(ctx, &j)
+	switch {
+	case res.Code == com.CodeCanceled, ctx.Err() != nil && !res.Success():
+		j.STATE = JobCanceled
+	case res.Success():
+		j.STATE = JobSucceeded
+	default:
+		j.STATE = JobFailed
+	}
+	j.FINISHED = time.Now()
+	j.RESULT = &res
+	GetManager().SaveJob(j)
+	log.Println("GO-WOXY Core - Job", j.ID, j.COMMAND, j.MODULES, j.STATE)
+}
+
+//startModuleJob - Run command request on module in background
+func startModuleJob(mc *ModuleConfig, cr com.CommandRequest, traceparent string) com.CommandResult {
+	modules := []string{mc.NAME}
+	if name := startedModule(mc, cr); name != mc.NAME {
+		modules = append(modules, name)
+	}
+	m := *mc
+	return startJob(cr.Command, modules, func(ctx context.Context, j *Job) com.CommandResult {
+		return runModuleJob(ctx, j, &m, cr, traceparent)
+	})
+}
+
+//startBroadcastJob - Run command request on modules of its target and selector in background
+func startBroadcastJob(cr com.CommandRequest, traceparent string) com.CommandResult {
+	target := cr.Target
+	if target == "" {
+		target = "*"
+	}
+	mods, err := GetManager().GetConfig().selectModules(target, cr.Selector)
+	if err != nil {
+		return com.NewCommandResult(cr.Command, "", err, 0)
+	}
+
+	var modules []string
+	for _, m := range mods {
+		modules = append(modules, m.NAME)
+	}
+	return startJob(cr.Command, modules, func(ctx context.Context, j *Job) com.CommandResult {
+		return broadcastCommand(cr, func(mc *ModuleConfig, cr com.CommandRequest) com.CommandResult {
+			return runModuleJob(ctx, j, mc, cr, traceparent)
+		})
+	})
+}
+
+//startedModule - Get module started by command request, the one it runs on unless Start names another one
+func startedModule(mc *ModuleConfig, cr com.CommandRequest) string {
+	if cr.Command == "Start" && cr.Args["module"] != "" {
+		return cr.Args["module"]
+	} else if cr.Command == "Start" && cr.Content != "" {
+		return cr.Content
+	}
+	return mc.NAME
+}
+
+//runModuleJob - Run command request on module, then wait for modules it starts or deploys to be online
+func runModuleJob(ctx context.Context, j *Job, mc *ModuleConfig, cr com.CommandRequest, traceparent string) com.CommandResult {
+	start := time.Now()
+	if ctx.Err() != nil {
+		return jobCanceled(cr.Command, mc.NAME, start)
+	}
+
+	j.step(mc.NAME + " : " + cr.Command)
+	res := runModuleRequest(ctx, mc, cr, traceparent)
+	if !res.Success() {
+		return res
+	}
+
+	var err error
+	switch cr.Command {
+	case "Start", "Restart", "Rollback":
+		name := startedModule(mc, cr)
+		j.step(name + " : waiting online")
+		if err = waitModuleOnline(ctx, name, GetManager().GetConfig().JOBS.TIMEOUT); ctx.Err() != nil {
+			//ABORT START OF MODULE NOT ONLINE YET
+			if m := GetManager().GetModule(name); !m.STATE.running() {
+				if e := m.Kill(); e == nil {
+					m.setState(Stopped)
+				}
+			}
+		}
+	case "Deploy":
+		var d Deployment
+		if err = json.Unmarshal(res.Data, &d); err == nil {
+			j.step(mc.NAME + " : waiting deployment " + d.ID)
+			err = waitDeployment(ctx, d.ID)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return jobCanceled(cr.Command, mc.NAME, start)
+	} else if err != nil {
+		res = com.NewCommandResult(cr.Command, "", err, time.Since(start))
+		res.Module = mc.NAME
+		return res
+	}
+	j.step(mc.NAME + " : done")
+	res.Duration = time.Since(start)
+	return res
+}
+
+//jobCanceled - Result of canceled job command
+func jobCanceled(command string, module string, start time.Time) com.CommandResult {
+	res := com.NewCommandResult(command, "", com.NewCommandError(com.CodeCanceled, "Job canceled"), time.Since(start))
+	res.Module = module
+	return res
+}
+
+//waitModuleOnline - Wait for module to be online, until timeout (default : 1 minute) or ctx is done
+func waitModuleOnline(ctx context.Context, name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		switch GetManager().GetModule(name).STATE {
+		case Online, LimitExceeded:
+			return nil
+		case Failed, Error:
+			return errors.New("module " + name + " failed to start")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return errors.New("module " + name + " not online after " + timeout.String())
+		case <-tick.C:
+		}
+	}
+}
+
+//waitDeployment - Wait for deployment to finish or ctx to be done, deployment itself is not canceled
+func waitDeployment(ctx context.Context, id string) error {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		for _, d := range GetManager().GetDeployments() {
+			if d.ID != id {
+				continue
+			} else if d.STATUS == DeploySuccess {
+				return nil
+			} else if d.STATUS == DeployFailed {
+				return errors.New("deployment " + id + " failed : " + lastMessage(d.MESSAGES))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+func lastMessage(messages []string) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1]
+}
+
+/* ---------------------------JOB COMMANDS----------------------------*/
+
+//moduleJob - Get job by id, only jobs running on module are found from modules other than hub
+func moduleJob(mc *ModuleConfig, id string) (Job, error) {
+	j, ok := GetManager().GetJob(id)
+	if !ok || (mc.NAME != "hub" && !j.hasModule(mc.NAME)) {
+		return j, com.NewCommandError(com.CodeNotFound, "Job "+id+" not found")
+	}
+	return j, nil
+}
+
+func jobsModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	res := []Job{}
+	for _, j := range GetManager().GetJobs() {
+		if mc.NAME == "hub" || j.hasModule(mc.NAME) {
+			res = append(res, j)
+		}
+	}
+
+	rb, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
+}
+
+func jobModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	j, err := moduleJob(mc, args.String("id"))
+	if err != nil {
+		return "", err
+	}
+
+	//LONG POLLING : WAIT FOR JOB TO FINISH
+	if wait := args.Duration("wait"); wait > 0 && !j.finished() {
+		if wait > maxJobWait {
+			wait = maxJobWait
+		}
+		select {
+		case <-j.done:
+		case <-time.After(wait):
+		}
+		j, _ = GetManager().GetJob(j.ID)
+	}
+
+	rb, err := json.Marshal(j)
+	if err != nil {
+		return "", err
+	}
+	return string(rb), nil
+}
+
+func cancelJobModuleCommand(ctx context.Context, r *com.Request, mc *ModuleConfig, args com.Args) (string, error) {
+	j, err := moduleJob(mc, args.String("id"))
+	if err != nil {
+		return "", err
+	} else if j.finished() {
+		return "", com.NewCommandError(com.CodeConflict, "Job "+j.ID+" already "+string(j.STATE))
+	}
+	j.cancel()
+	return "Success", nil
+}
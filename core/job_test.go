@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//jobTestStart - Start job running run, returning its id
+func jobTestStart(t *testing.T, run func(ctx context.Context, j *Job) com.CommandResult) string {
+	res := startJob("Start", []string{"m"}, run)
+	var j Job
+	if res.Code != com.CodeAccepted || json.Unmarshal(res.Data, &j) != nil || j.ID == "" {
+		t.Fatalf("start job result = %+v", res)
+	}
+	return j.ID
+}
+
+//jobTestPoll - Long poll job through Job command
+func jobTestPoll(t *testing.T, id string, wait string) Job {
+	hub := ModuleConfig{NAME: "hub"}
+	var r com.Request = &com.CommandRequest{}
+	out, err := jobModuleCommand(context.Background(), &r, &hub, com.Args{"id": id, "wait": wait})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var j Job
+	if err := json.Unmarshal([]byte(out), &j); err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func TestJobCancel(t *testing.T) {
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{"m": {NAME: "m", STATE: Loading}}})
+
+	//JOB WAITS FOR A MODULE THAT NEVER COMES ONLINE
+	id := jobTestStart(t, func(ctx context.Context, j *Job) com.CommandResult {
+		start := time.Now()
+		if err := waitModuleOnline(ctx, "m", time.Minute); err != nil {
+			return com.NewCommandResult("Start", "", err, time.Since(start))
+		}
+		return com.NewCommandResult("Start", "", nil, time.Since(start))
+	})
+	if j := jobTestPoll(t, id, "100ms"); j.STATE != JobRunning {
+		t.Fatalf("job state before cancel = %s, want %s", j.STATE, JobRunning)
+	}
+
+	hub := ModuleConfig{NAME: "hub"}
+	var r com.Request = &com.CommandRequest{}
+	if _, err := cancelJobModuleCommand(context.Background(), &r, &hub, com.Args{"id": id}); err != nil {
+		t.Fatalf("cancel : %v", err)
+	}
+	j := jobTestPoll(t, id, "5s")
+	if j.STATE != JobCanceled || j.RESULT == nil || j.FINISHED.IsZero() {
+		t.Fatalf("job after cancel = %s, result %v", j.STATE, j.RESULT)
+	}
+
+	//FINISHED JOB CAN NOT BE CANCELED AGAIN
+	_, err := cancelJobModuleCommand(context.Background(), &r, &hub, com.Args{"id": id})
+	if ce, ok := err.(*com.CommandError); !ok || ce.Code != com.CodeConflict {
+		t.Errorf("cancel finished job error = %v, want %s", err, com.CodeConflict)
+	}
+}
+
+func TestJobLongPoll(t *testing.T) {
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{"m": {NAME: "m"}}})
+
+	release := make(chan struct{})
+	id := jobTestStart(t, func(ctx context.Context, j *Job) com.CommandResult {
+		<-release
+		return com.NewCommandResult("Start", "done", nil, 0)
+	})
+
+	//WAIT EXPIRES WHILE JOB IS STILL RUNNING
+	start := time.Now()
+	if j := jobTestPoll(t, id, "50ms"); j.STATE != JobRunning || j.RESULT != nil {
+		t.Errorf("job polled before end = %s, want %s", j.STATE, JobRunning)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("poll returned after %s, before wait", d)
+	}
+
+	//POLL RETURNS AS SOON AS JOB IS DONE, NOT AT END OF WAIT
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	start = time.Now()
+	j := jobTestPoll(t, id, "30s")
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("poll returned after %s, job finished after 50ms", d)
+	}
+	if j.STATE != JobSucceeded || j.RESULT == nil || j.RESULT.Message != "done" {
+		t.Errorf("job after poll = %s, result %v", j.STATE, j.RESULT)
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+)
+
+//LimitsConfig - Resources limits of module process
+type LimitsConfig struct {
+	CPU        float64
+	MEMORY     string
+	OPEN_FILES uint64
+	PROCESSES  uint64
+}
+
+//memoryBytes - Parse MEMORY limit (example : 512M, 2G, 1048576)
+func (l LimitsConfig) memoryBytes() (uint64, error) {
+	s := strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(l.MEMORY), "B"))
+	if s == "" {
+		return 0, nil
+	}
+
+	mult := uint64(1)
+	switch s[len(s)-1] {
+	case 'K':
+		mult = 1 << 10
+	case 'M':
+		mult = 1 << 20
+	case 'G':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || v == 0 {
+		return 0, errors.New("invalid memory limit " + l.MEMORY)
+	}
+	return v * mult, nil
+}
+
+func (l LimitsConfig) empty() bool {
+	return l == LimitsConfig{}
+}
+
+//limitEvent - Report a limit breach as module state and log event, called by cgroup watcher while module runs
+func (mc *ModuleConfig) limitEvent(instance string, msg string) {
+	log.Println("GO-WOXY Core - Mod", mc.NAME, "limit exceeded :", msg)
+	GetManager().GetLogStore(mc.NAME).Event(instance, "limit exceeded : "+msg)
+
+	GetManager().UpdateModule(mc.NAME, func(m *ModuleConfig) {
+		m.STATE = LimitExceeded
+	})
+}
@@ -0,0 +1,239 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//cgroupRoot - cgroup v2 group holding one group per module
+const cgroupRoot = "/sys/fs/cgroup/go-woxy"
+
+//cgroupGate - Wrapper holding module process until core moved it in its cgroup, then running module command
+const cgroupGate = `read -r go <&3 || exit 1; exec "$@" 3<&-`
+
+//processLimits - Limits of a module instance, set up before module binary runs
+type processLimits struct {
+	cgroup  string
+	gate    *os.File
+	gateR   *os.File
+	prlimit []string
+	stop    chan bool
+	watched chan struct{}
+}
+
+//prepareLimits - Create module cgroup and rlimits wrapper, process starts with limits already in place
+func (mc *ModuleConfig) prepareLimits() (*processLimits, error) {
+	pl := &processLimits{stop: make(chan bool)}
+	l := mc.EXE.LIMITS
+	if l.empty() {
+		return pl, nil
+	}
+	mem, err := l.memoryBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := mc.setupCgroup(mem)
+	if err != nil {
+		log.Println("GO-WOXY Core - cgroup v2 not available for mod", mc.NAME, ", rlimits only :", err)
+		if l.PROCESSES > 0 {
+			log.Println("GO-WOXY Core - Process limit of mod", mc.NAME, "ignored : only enforced by cgroup")
+		}
+		if l.CPU > 0 {
+			log.Println("GO-WOXY Core - CPU limit of mod", mc.NAME, "ignored : only enforced by cgroup")
+		}
+	} else if pl.gateR, pl.gate, err = os.Pipe(); err != nil {
+		os.Remove(dir)
+		return nil, err
+	} else {
+		pl.cgroup = dir
+		//MEMORY IS LIMITED BY CGROUP, RLIMIT ONLY WHEN IT IS MISSING
+		mem = 0
+	}
+
+	//RLIMITS SET BY prlimit BEFORE IT EXECS MODULE BINARY, RLIMIT_NPROC IS NOT USED : IT COUNTS ALL PROCESSES OF USER AND DOES NOT APPLY TO ROOT
+	var rlimits []string
+	if l.OPEN_FILES > 0 {
+		rlimits = append(rlimits, "--nofile="+strconv.FormatUint(l.OPEN_FILES, 10))
+	}
+	if mem > 0 {
+		rlimits = append(rlimits, "--data="+strconv.FormatUint(mem, 10))
+	}
+	if len(rlimits) > 0 {
+		path, err := exec.LookPath("prlimit")
+		if err != nil {
+			pl.release()
+			return nil, errors.New("prlimit command (util-linux) needed for rlimits : " + err.Error())
+		}
+		pl.prlimit = append([]string{path}, rlimits...)
+	}
+	return pl, nil
+}
+
+//command - Get command running module binary, through prlimit when rlimits are set and cgroup gate when cgroup is used
+func (pl *processLimits) command(bin string, args []string) (string, []string) {
+	name := bin
+	if len(pl.prlimit) > 0 {
+		name, args = pl.prlimit[0], append(append(append([]string{}, pl.prlimit[1:]...), "--", bin), args...)
+	}
+	if pl.cgroup == "" {
+		return name, args
+	}
+	return "/bin/sh", append([]string{"-c", cgroupGate, "woxy-cgroup-gate", name}, args...)
+}
+
+//prepare - Give cgroup gate read end to process as fd 3
+func (pl *processLimits) prepare(cmd *exec.Cmd) {
+	if pl.gateR != nil {
+		cmd.ExtraFiles = []*os.File{pl.gateR}
+	}
+}
+
+//join - Move started process in module cgroup, then let it run module binary
+//Process exits without running module when it can not be moved
+func (pl *processLimits) join(pid int) error {
+	if pl.gate == nil {
+		return nil
+	}
+	pl.gateR.Close()
+	pl.gateR = nil
+	defer func() {
+		pl.gate.Close()
+		pl.gate = nil
+	}()
+
+	if err := ioutil.WriteFile(filepath.Join(pl.cgroup, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return errors.New("moving process in cgroup : " + err.Error())
+	}
+	_, err := pl.gate.Write([]byte("go\n"))
+	return err
+}
+
+//watch - Report breaches of module cgroup limits until limits are released
+func (pl *processLimits) watch(mc *ModuleConfig, instance string) {
+	if pl.cgroup != "" {
+		pl.watched = make(chan struct{})
+		go func() {
+			mc.watchCgroup(pl.cgroup, instance, pl.stop)
+			close(pl.watched)
+		}()
+	}
+}
+
+//release - Kill processes left in module cgroup and remove it
+func (pl *processLimits) release() {
+	close(pl.stop)
+	if pl.watched != nil {
+		<-pl.watched
+	}
+	for _, f := range []*os.File{pl.gate, pl.gateR} {
+		if f != nil {
+			f.Close()
+		}
+	}
+	if pl.cgroup == "" {
+		return
+	}
+
+	//cgroup.kill NEEDS LINUX 5.14, GROUP CAN ONLY BE REMOVED ONCE EMPTY
+	ioutil.WriteFile(filepath.Join(pl.cgroup, "cgroup.kill"), []byte("1"), 0644)
+	for i := 0; i < 50; i++ {
+		if err := os.Remove(pl.cgroup); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Println("GO-WOXY Core - Error removing cgroup", pl.cgroup, ": processes still running")
+}
+
+//setupCgroup - Create module cgroup with cpu, memory and pids limits
+func (mc *ModuleConfig) setupCgroup(mem uint64) (string, error) {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return "", errors.New("cgroup v2 not mounted")
+	}
+	if err := os.MkdirAll(cgroupRoot, 0755); err != nil {
+		return "", err
+	}
+
+	//CONTROLLERS ENABLED ONE BY ONE, SOME MAY ALREADY BE OR BE MISSING
+	for _, c := range []string{"+cpu", "+memory", "+pids"} {
+		ioutil.WriteFile(filepath.Join(filepath.Dir(cgroupRoot), "cgroup.subtree_control"), []byte(c), 0644)
+		ioutil.WriteFile(filepath.Join(cgroupRoot, "cgroup.subtree_control"), []byte(c), 0644)
+	}
+
+	dir := filepath.Join(cgroupRoot, mc.NAME)
+	if err := os.Mkdir(dir, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	l := mc.EXE.LIMITS
+	files := map[string]string{}
+	if mem > 0 {
+		files["memory.max"] = strconv.FormatUint(mem, 10)
+	}
+	if l.CPU > 0 {
+		files["cpu.max"] = strconv.Itoa(int(l.CPU*100000)) + " 100000"
+	}
+	if l.PROCESSES > 0 {
+		files["pids.max"] = strconv.FormatUint(l.PROCESSES, 10)
+	}
+
+	for _, f := range []string{"memory.max", "cpu.max", "pids.max"} {
+		if v, ok := files[f]; ok {
+			if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(v), 0644); err != nil {
+				os.Remove(dir)
+				return "", err
+			}
+		}
+	}
+	return dir, nil
+}
+
+//watchCgroup - Report OOM kills and process count breaches of module cgroup, last check is made once stopped
+func (mc *ModuleConfig) watchCgroup(dir string, instance string, stop chan bool) {
+	oom := cgroupEvent(dir, "memory.events", "oom_kill")
+	pids := cgroupEvent(dir, "pids.events", "max")
+
+	for done := false; !done; {
+		select {
+		case <-stop:
+			done = true
+		case <-time.After(2 * time.Second):
+		}
+
+		if n := cgroupEvent(dir, "memory.events", "oom_kill"); n > oom {
+			oom = n
+			mc.limitEvent(instance, "memory limit "+mc.EXE.LIMITS.MEMORY+" reached, process killed")
+		}
+		if n := cgroupEvent(dir, "pids.events", "max"); n > pids {
+			pids = n
+			mc.limitEvent(instance, "process limit "+strconv.FormatUint(mc.EXE.LIMITS.PROCESSES, 10)+" reached")
+		}
+	}
+}
+
+//cgroupEvent - Read counter of a cgroup events file
+func cgroupEvent(dir string, file string, key string) int {
+	b, err := ioutil.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	for _, l := range strings.Split(string(b), "\n") {
+		f := strings.Fields(l)
+		if len(f) == 2 && f[0] == key {
+			n, _ := strconv.Atoi(f[1])
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestProcessLimitsCommand(t *testing.T) {
+	tests := []struct {
+		cgroup  string
+		prlimit []string
+		args    []string
+		name    string
+		want    []string
+	}{
+		{"", nil, []string{"-v"}, "/mods/.a-bin", []string{"-v"}},
+		{"", []string{"/usr/bin/prlimit", "--nofile=64"}, nil, "/usr/bin/prlimit", []string{"--nofile=64", "--", "/mods/.a-bin"}},
+		{"", []string{"/usr/bin/prlimit", "--nofile=64", "--data=1024"}, []string{"-v", "--port=1"}, "/usr/bin/prlimit", []string{"--nofile=64", "--data=1024", "--", "/mods/.a-bin", "-v", "--port=1"}},
+		{"/cg/a", nil, []string{"-v"}, "/bin/sh", []string{"-c", cgroupGate, "woxy-cgroup-gate", "/mods/.a-bin", "-v"}},
+		{"/cg/a", []string{"/usr/bin/prlimit", "--nofile=64"}, nil, "/bin/sh", []string{"-c", cgroupGate, "woxy-cgroup-gate", "/usr/bin/prlimit", "--nofile=64", "--", "/mods/.a-bin"}},
+	}
+	for _, tt := range tests {
+		pl := processLimits{cgroup: tt.cgroup, prlimit: tt.prlimit}
+		name, args := pl.command("/mods/.a-bin", tt.args)
+		if name != tt.name || !reflect.DeepEqual(args, tt.want) {
+			t.Errorf("command(%v) = %s %v, want %s %v", tt.prlimit, name, args, tt.name, tt.want)
+		}
+	}
+}
+
+func TestCgroupGate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "woxy-cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	//REGULAR FILE STANDS FOR cgroup.procs, MISSING DIRECTORY FOR A CGROUP PROCESS CAN NOT JOIN
+	tests := []struct {
+		name    string
+		cgroup  string
+		wantErr bool
+	}{
+		{"joined", dir, false},
+		{"join failed", filepath.Join(dir, "missing"), true},
+	}
+	for _, tt := range tests {
+		pl := &processLimits{cgroup: tt.cgroup, stop: make(chan bool)}
+		if pl.gateR, pl.gate, err = os.Pipe(); err != nil {
+			t.Fatal(err)
+		}
+		name, args := pl.command("/bin/echo", []string{"module", "running"})
+		cmd := exec.Command(name, args...)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		pl.prepare(cmd)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		err := pl.join(cmd.Process.Pid)
+		cmd.Wait()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s : join error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			if out.Len() > 0 {
+				t.Errorf("%s : module ran outside of its cgroup : %q", tt.name, out.String())
+			}
+			continue
+		}
+		if out.String() != "module running\n" {
+			t.Errorf("%s : output = %q", tt.name, out.String())
+		}
+		if b, _ := ioutil.ReadFile(filepath.Join(dir, "cgroup.procs")); string(b) != strconv.Itoa(cmd.Process.Pid) {
+			t.Errorf("%s : cgroup.procs = %q, want pid %d", tt.name, b, cmd.Process.Pid)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import (
+	"log"
+	"os/exec"
+)
+
+//processLimits - Resources limits are only supported on linux
+type processLimits struct{}
+
+//prepareLimits - Resources limits are only supported on linux
+func (mc *ModuleConfig) prepareLimits() (*processLimits, error) {
+	if !mc.EXE.LIMITS.empty() {
+		log.Println("GO-WOXY Core - Resources limits of mod", mc.NAME, "ignored : only supported on linux")
+	}
+	return &processLimits{}, nil
+}
+
+func (pl *processLimits) command(bin string, args []string) (string, []string) {
+	return bin, args
+}
+
+func (pl *processLimits) prepare(cmd *exec.Cmd) {}
+
+func (pl *processLimits) join(pid int) error {
+	return nil
+}
+
+func (pl *processLimits) watch(mc *ModuleConfig, instance string) {}
+
+func (pl *processLimits) release() {}
@@ -0,0 +1,29 @@
+package core
+
+import "testing"
+
+func TestLimitsMemoryBytes(t *testing.T) {
+	tests := []struct {
+		memory string
+		want   uint64
+		err    bool
+	}{
+		{"", 0, false},
+		{"1048576", 1048576, false},
+		{"512K", 512 << 10, false},
+		{"512M", 512 << 20, false},
+		{"2g", 2 << 30, false},
+		{"2GB", 2 << 30, false},
+		{" 64M ", 64 << 20, false},
+		{"0", 0, true},
+		{"M", 0, true},
+		{"12X", 0, true},
+		{"-1G", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := LimitsConfig{MEMORY: tt.memory}.memoryBytes()
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("memoryBytes(%q) = %d, %v, want %d, error %v", tt.memory, got, err, tt.want, tt.err)
+		}
+	}
+}
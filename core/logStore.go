@@ -0,0 +1,337 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//LogConfig - Module log capture configuration
+type LogConfig struct {
+	BUFFER    int
+	MAX_AGE   time.Duration
+	MAX_FILES int
+	MAX_SIZE  int64
+	PATH      string
+}
+
+//LogLine - Line captured from a module output
+type LogLine struct {
+	Instance  string
+	Level     string
+	Module    string
+	RequestID string `json:",omitempty"`
+	Stream    string
+	Text      string
+	Time      time.Time
+}
+
+//String - Format LogLine for plain text output
+func (l LogLine) String() string {
+	return l.Time.Format("2006-01-02T15:04:05.000Z07:00") + " [" + l.Module + "/" + l.Instance + "] " + l.Stream + " " + l.Level + " " + l.Text
+}
+
+//LogQuery - Filters applied on a module log
+type LogQuery struct {
+	Grep      *regexp.Regexp
+	Level     string
+	RequestID string
+	Since     time.Time
+	Tail      int
+}
+
+//LogStore - Captured output of a module, kept in a ring buffer and in rotated files
+type LogStore struct {
+	module    string
+	instances int
+
+	mux         sync.Mutex
+	lines       []LogLine
+	next        int
+	full        bool
+	file        *rotatingFile
+	masks       []string
+	subscribers map[chan LogLine]bool
+}
+
+var logLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL", "PANIC"}
+
+var levelAliases = map[string]string{
+	"TRC": "TRACE", "TRACE": "TRACE",
+	"DBG": "DEBUG", "DEBUG": "DEBUG",
+	"INF": "INFO", "INFO": "INFO",
+	"WRN": "WARN", "WARN": "WARN", "WARNING": "WARN",
+	"ERR": "ERROR", "ERROR": "ERROR",
+	"FTL": "FATAL", "FATAL": "FATAL",
+	"PNC": "PANIC", "PANIC": "PANIC",
+}
+
+var (
+	ansiRegexp      = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+	jsonLevelRegexp = regexp.MustCompile(`"level"\s*:\s*"([a-zA-Z]+)"`)
+	requestIDRegexp = regexp.MustCompile(`request_id"?\s*[=:]\s*"?([A-Za-z0-9._-]+)`)
+	textLevelRegexp = regexp.MustCompile(`\b(TRC|TRACE|DBG|DEBUG|INF|INFO|WRN|WARN|WARNING|ERR|ERROR|FTL|FATAL|PNC|PANIC)\b`)
+)
+
+func newLogStore(module string, c LogConfig) *LogStore {
+	ls := LogStore{module: module, lines: make([]LogLine, c.BUFFER)}
+	if c.PATH != "" {
+		ls.file = newRotatingFile(filepath.Join(c.PATH, module+".log"), c.MAX_SIZE*1024*1024, c.MAX_AGE, c.MAX_FILES)
+	}
+	return &ls
+}
+
+//NewInstance - Get a new instance identifier for a module launch
+func (ls *LogStore) NewInstance() string {
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+	ls.instances++
+	return strconv.Itoa(ls.instances)
+}
+
+//Instances - Get number of module instances started
+func (ls *LogStore) Instances() int {
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+	return ls.instances
+}
+
+//SetMasks - Set values replaced by **** in captured lines
+func (ls *LogStore) SetMasks(masks []string) {
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+	ls.masks = masks
+}
+
+//Writer - Get a writer tagging each line with instance and stream
+func (ls *LogStore) Writer(instance string, stream string) io.WriteCloser {
+	return &logWriter{store: ls, instance: instance, stream: stream}
+}
+
+//Event - Add a core event line about module instance to LogStore
+func (ls *LogStore) Event(instance string, text string) {
+	ls.Add(LogLine{Instance: instance, Level: "WARN", Module: ls.module, Stream: "event", Text: text, Time: time.Now()})
+}
+
+//Add - Add a line to LogStore
+func (ls *LogStore) Add(l LogLine) {
+	ls.mux.Lock()
+	defer ls.mux.Unlock()
+
+	for _, m := range ls.masks {
+		l.Text = strings.ReplaceAll(l.Text, m, "****")
+	}
+
+	if len(ls.lines) > 0 {
+		ls.lines[ls.next] = l
+		ls.next = (ls.next + 1) % len(ls.lines)
+		if ls.next == 0 {
+			ls.full = true
+		}
+	}
+
+	//SLOW SUBSCRIBERS MISS LINES INSTEAD OF BLOCKING MODULE OUTPUT
+	for ch := range ls.subscribers {
+		select {
+		case ch <- l:
+		default:
+		}
+	}
+
+	if ls.file != nil {
+		b, err := json.Marshal(l)
+		if err == nil {
+			_, err = ls.file.Write(append(b, '\n'))
+		}
+		if err != nil {
+			log.Println("GO-WOXY Core - Error writing log of", ls.module, ":", err)
+		}
+	}
+}
+
+//Subscribe - Get a channel receiving new lines until unsubscribe is called
+func (ls *LogStore) Subscribe() (chan LogLine, func()) {
+	ch := make(chan LogLine, 256)
+
+	ls.mux.Lock()
+	if ls.subscribers == nil {
+		ls.subscribers = map[chan LogLine]bool{}
+	}
+	ls.subscribers[ch] = true
+	ls.mux.Unlock()
+
+	return ch, func() {
+		ls.mux.Lock()
+		delete(ls.subscribers, ch)
+		ls.mux.Unlock()
+	}
+}
+
+//Query - Get buffered lines matching LogQuery, oldest first
+func (ls *LogStore) Query(q LogQuery) []LogLine {
+	ls.mux.Lock()
+	var all []LogLine
+	if ls.full {
+		all = append(all, ls.lines[ls.next:]...)
+	}
+	all = append(all, ls.lines[:ls.next]...)
+	ls.mux.Unlock()
+
+	var res []LogLine
+	for _, l := range all {
+		if q.match(l) {
+			res = append(res, l)
+		}
+	}
+
+	if q.Tail > 0 && len(res) > q.Tail {
+		res = res[len(res)-q.Tail:]
+	}
+	return res
+}
+
+//Close - Close log files
+func (ls *LogStore) Close() error {
+	if ls.file != nil {
+		return ls.file.Close()
+	}
+	return nil
+}
+
+func (q LogQuery) match(l LogLine) bool {
+	if !q.Since.IsZero() && l.Time.Before(q.Since) {
+		return false
+	}
+	if q.Level != "" && levelIndex(l.Level) < levelIndex(q.Level) {
+		return false
+	}
+	if q.Grep != nil && !q.Grep.MatchString(l.Text) {
+		return false
+	}
+	if q.RequestID != "" && l.RequestID != q.RequestID {
+		return false
+	}
+	return true
+}
+
+//parseLogQuery - Parse LogQuery from a query string
+//(example : tail=100&since=10m&grep=panic&level=warn&request_id=abc)
+func parseLogQuery(s string) (LogQuery, error) {
+	var q LogQuery
+
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return q, err
+	}
+
+	if t := v.Get("tail"); t != "" {
+		if q.Tail, err = strconv.Atoi(t); err != nil || q.Tail < 0 {
+			return q, errors.New("invalid tail : " + t)
+		}
+	}
+
+	if t := v.Get("since"); t != "" {
+		if d, err := time.ParseDuration(t); err == nil {
+			q.Since = time.Now().Add(-d)
+		} else if q.Since, err = time.Parse(time.RFC3339, t); err != nil {
+			return q, errors.New("invalid since : " + t)
+		}
+	}
+
+	if t := v.Get("grep"); t != "" {
+		if q.Grep, err = regexp.Compile(t); err != nil {
+			return q, err
+		}
+	}
+
+	if t := v.Get("request_id"); t != "" {
+		q.RequestID = t
+	}
+
+	if t := v.Get("level"); t != "" {
+		if q.Level = levelAliases[strings.ToUpper(t)]; q.Level == "" {
+			return q, errors.New("invalid level : " + t)
+		}
+	}
+	return q, nil
+}
+
+func levelIndex(level string) int {
+	for i := range logLevels {
+		if logLevels[i] == level {
+			return i
+		}
+	}
+	return 0
+}
+
+func detectRequestID(text string) string {
+	if m := requestIDRegexp.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func detectLevel(text string) string {
+	if m := jsonLevelRegexp.FindStringSubmatch(text); m != nil {
+		if l := levelAliases[strings.ToUpper(m[1])]; l != "" {
+			return l
+		}
+	}
+	if m := textLevelRegexp.FindStringSubmatch(text); m != nil {
+		return levelAliases[m[1]]
+	}
+	return "INFO"
+}
+
+//logWriter - io.WriteCloser splitting output in LogLine
+type logWriter struct {
+	store    *LogStore
+	instance string
+	stream   string
+	buf      []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+//Close - Flush last unterminated line
+func (w *logWriter) Close() error {
+	if len(w.buf) > 0 {
+		w.line(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *logWriter) line(b []byte) {
+	text := ansiRegexp.ReplaceAllString(strings.TrimRight(string(b), "\r"), "")
+	w.store.Add(LogLine{
+		Instance:  w.instance,
+		Level:     detectLevel(text),
+		Module:    w.store.module,
+		RequestID: detectRequestID(text),
+		Stream:    w.stream,
+		Text:      text,
+		Time:      time.Now(),
+	})
+}
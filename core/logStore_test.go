@@ -0,0 +1,179 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogStoreQuery(t *testing.T) {
+	ls := newLogStore("m", LogConfig{BUFFER: 4})
+	ls.SetMasks([]string{"s3cr3t"})
+	w := ls.Writer("1", "stdout")
+	w.Write([]byte("zero\nstarting INF\n{\"level\":\"error\",\"request_id\":\"abc\",\"msg\":\"boom\"}\n"))
+	w.Write([]byte("\x1b[31mWRN\x1b[0m token s3cr3t\r\nlast request_id=def"))
+	w.Close()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"buffer keeps last lines", "", []string{"starting INF", `{"level":"error","request_id":"abc","msg":"boom"}`, "WRN token ****", "last request_id=def"}},
+		{"tail", "tail=2", []string{"WRN token ****", "last request_id=def"}},
+		{"level", "level=warn", []string{`{"level":"error","request_id":"abc","msg":"boom"}`, "WRN token ****"}},
+		{"grep", "grep=^last", []string{"last request_id=def"}},
+		{"request id", "request_id=abc", []string{`{"level":"error","request_id":"abc","msg":"boom"}`}},
+		{"since", "since=1h&tail=1", []string{"last request_id=def"}},
+		{"future", "since=" + time.Now().Add(time.Hour).Format(time.RFC3339), nil},
+	}
+	for _, tt := range tests {
+		q, err := parseLogQuery(tt.query)
+		if err != nil {
+			t.Fatalf("%s : %v", tt.name, err)
+		}
+		var got []string
+		for _, l := range ls.Query(q) {
+			got = append(got, l.Text)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s : got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseLogQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"tail=10&since=5m&grep=panic&level=WRN&request_id=x", false},
+		{"since=2020-01-02T15:04:05Z", false},
+		{"tail=-1", true},
+		{"tail=a", true},
+		{"since=yesterday", true},
+		{"grep=(", true},
+		{"level=loud", true},
+		{"%zz", true},
+	}
+	for _, tt := range tests {
+		if _, err := parseLogQuery(tt.query); (err != nil) != tt.wantErr {
+			t.Errorf("parseLogQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		text  string
+		level string
+		id    string
+	}{
+		{"plain line", "INFO", ""},
+		{`{"level":"debug","request_id":"r-1"}`, "DEBUG", "r-1"},
+		{`{"level":"verbose"} WARNING`, "WARN", ""},
+		{"12:00 ERR failed request_id=a.b_c", "ERROR", "a.b_c"},
+		{"INFORMATION only", "INFO", ""},
+		{"PANIC: runtime error", "PANIC", ""},
+	}
+	for _, tt := range tests {
+		if got := detectLevel(tt.text); got != tt.level {
+			t.Errorf("detectLevel(%q) = %s, want %s", tt.text, got, tt.level)
+		}
+		if got := detectRequestID(tt.text); got != tt.id {
+			t.Errorf("detectRequestID(%q) = %s, want %s", tt.text, got, tt.id)
+		}
+	}
+}
+
+func TestLogStoreFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "woxy-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ls := newLogStore("m", LogConfig{BUFFER: 10, PATH: dir})
+	ls.Event("1", "limit reached")
+	ls.Writer("1", "stderr").Write([]byte("ERR failed\n"))
+	ls.Close()
+
+	f, err := os.Open(filepath.Join(dir, "m.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	want := []LogLine{
+		{Instance: "1", Level: "WARN", Module: "m", Stream: "event", Text: "limit reached"},
+		{Instance: "1", Level: "ERROR", Module: "m", Stream: "stderr", Text: "ERR failed"},
+	}
+	s := bufio.NewScanner(f)
+	for i := 0; s.Scan(); i++ {
+		var l LogLine
+		if err := json.Unmarshal(s.Bytes(), &l); err != nil {
+			t.Fatalf("invalid line %q : %v", s.Text(), err)
+		}
+		l.Time = time.Time{}
+		if i >= len(want) || l != want[i] {
+			t.Errorf("line %d = %+v", i, l)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "woxy-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		maxAge   time.Duration
+		maxFiles int
+		writes   int
+		rotated  int
+	}{
+		{"no limit", 0, 0, 0, 8, 0},
+		{"size", 100, 0, 0, 8, 3},
+		{"size with max files", 100, 0, 2, 8, 2},
+		{"age", 0, time.Millisecond, 1, 3, 1},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, strings.Replace(tt.name, " ", "-", -1)+".log")
+		rf := newRotatingFile(path, tt.maxSize, tt.maxAge, tt.maxFiles)
+		for i := 0; i < tt.writes; i++ {
+			if _, err := rf.Write([]byte(strings.Repeat("x", 39) + "\n")); err != nil {
+				t.Fatal(err)
+			}
+			//ROTATED FILE NAMES HAVE MILLISECOND PRECISION
+			time.Sleep(2 * time.Millisecond)
+		}
+		rf.Close()
+
+		rotated, _ := filepath.Glob(path + ".*")
+		if len(rotated) != tt.rotated {
+			t.Errorf("%s : %d rotated files, want %d", tt.name, len(rotated), tt.rotated)
+		}
+		for _, f := range append(rotated, path) {
+			if fi, err := os.Stat(f); err != nil {
+				t.Errorf("%s : %v", tt.name, err)
+			} else if tt.maxSize > 0 && fi.Size() > tt.maxSize {
+				t.Errorf("%s : %s size %d over max size", tt.name, f, fi.Size())
+			}
+		}
+		for _, f := range rotated {
+			if !regexp.MustCompile(`\.\d{8}-\d{6}\.\d{3}$`).MatchString(f) {
+				t.Errorf("%s : rotated file name %s", tt.name, f)
+			}
+		}
+	}
+}
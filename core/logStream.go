@@ -0,0 +1,182 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+//logFollower - Lines of one or many modules merged in one channel
+type logFollower struct {
+	format  string
+	history []LogLine
+	lines   chan LogLine
+	query   LogQuery
+	done    chan bool
+	stops   []func()
+}
+
+//openLogFollower - Check access and subscribe to modules asked in request
+//(query : modules=a,b&level=warn&grep=panic&tail=50&format=json)
+func openLogFollower(c *gin.Context) (*logFollower, int, error) {
+	if !hashMatchSecretHash(requestSecret(c.Request)) {
+		return nil, http.StatusUnauthorized, errors.New("Secret not matching with server")
+	}
+
+	names := strings.Split(c.Query("modules"), ",")
+	if c.Query("modules") == "" {
+		return nil, http.StatusBadRequest, errors.New("No module asked")
+	}
+
+	q, err := parseLogQuery(c.Request.URL.RawQuery)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	for _, n := range names {
+		if _, ok := GetManager().LookupModule(n); !ok {
+			return nil, http.StatusNotFound, errors.New("Module " + n + " not found")
+		}
+	}
+
+	f := logFollower{format: c.Query("format"), lines: make(chan LogLine, 256), query: q, done: make(chan bool)}
+	for _, n := range names {
+		ls := GetManager().GetLogStore(n)
+		if q.Tail > 0 {
+			f.history = append(f.history, ls.Query(q)...)
+		}
+		ch, stop := ls.Subscribe()
+		f.stops = append(f.stops, stop)
+		go f.forward(ch)
+	}
+
+	//MERGE HISTORY OF MODULES BY TIME, TAIL APPLIES TO MERGED LINES
+	sort.SliceStable(f.history, func(i, j int) bool { return f.history[i].Time.Before(f.history[j].Time) })
+	if q.Tail > 0 && len(f.history) > q.Tail {
+		f.history = f.history[len(f.history)-q.Tail:]
+	}
+	return &f, http.StatusOK, nil
+}
+
+func (f *logFollower) forward(ch chan LogLine) {
+	for {
+		select {
+		case l := <-ch:
+			if !f.query.match(l) {
+				continue
+			}
+			select {
+			case f.lines <- l:
+			case <-f.done:
+				return
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+//Close - Unsubscribe from all followed modules
+func (f *logFollower) Close() {
+	for _, stop := range f.stops {
+		stop()
+	}
+	close(f.done)
+}
+
+func (f *logFollower) encode(l LogLine) string {
+	if f.format == "json" {
+		b, _ := json.Marshal(l)
+		return string(b)
+	}
+	return l.String()
+}
+
+//logStream - Follow modules output with Server-Sent Events
+func logStream(c *gin.Context) {
+	f, code, err := openLogFollower(c)
+	if err != nil {
+		c.String(code, "%s", err.Error())
+		return
+	}
+	defer f.Close()
+
+	log.Println("GO-WOXY Core - Log stream opened from", c.Request.RemoteAddr, "for", c.Query("modules"))
+
+	for _, l := range f.history {
+		c.SSEvent("log", f.encode(l))
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case l := <-f.lines:
+			c.SSEvent("log", f.encode(l))
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().Format(time.RFC3339))
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
+
+//logWebSocket - Follow modules output with a WebSocket
+func logWebSocket(c *gin.Context) {
+	f, code, err := openLogFollower(c)
+	if err != nil {
+		c.String(code, "%s", err.Error())
+		return
+	}
+	defer f.Close()
+
+	log.Println("GO-WOXY Core - Log websocket opened from", c.Request.RemoteAddr, "for", c.Query("modules"))
+
+	//NO ORIGIN CHECK : ACCESS IS ALREADY CHECKED WITH SECRET
+	ws := websocket.Server{Handler: func(conn *websocket.Conn) {
+		closed := make(chan bool)
+		go func() {
+			//READ UNTIL CLIENT CLOSE CONNECTION
+			io.Copy(ioutil.Discard, conn)
+			close(closed)
+		}()
+
+		for _, l := range f.history {
+			if websocket.Message.Send(conn, f.encode(l)) != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case l := <-f.lines:
+				if websocket.Message.Send(conn, f.encode(l)) != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}}
+	ws.ServeHTTP(c.Writer, c.Request)
+}
+
+//requestSecret - Get secret from Authorization bearer header or secret query parameter
+func requestSecret(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.URL.Query().Get("secret")
+}
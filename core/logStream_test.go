@@ -0,0 +1,56 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogFollowerHistoryMerged(t *testing.T) {
+	cfg := &Config{LOG: LogConfig{BUFFER: 100}, SECRET: "s", MODULES: map[string]ModuleConfig{"a": {NAME: "a"}, "b": {NAME: "b"}}}
+	GetManager().SetState(cfg)
+
+	now := time.Now()
+	a, b := GetManager().GetLogStore("a"), GetManager().GetLogStore("b")
+	a.Add(LogLine{Module: "a", Text: "a1", Time: now.Add(-4 * time.Second)})
+	b.Add(LogLine{Module: "b", Text: "b1", Time: now.Add(-3 * time.Second)})
+	a.Add(LogLine{Module: "a", Text: "a2", Time: now.Add(-2 * time.Second)})
+	b.Add(LogLine{Module: "b", Text: "b2", Time: now.Add(-1 * time.Second)})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"modules=a,b&tail=10", []string{"a1", "b1", "a2", "b2"}},
+		{"modules=a,b&tail=3", []string{"b1", "a2", "b2"}},
+		{"modules=b,a&tail=2", []string{"a2", "b2"}},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/logs/stream?"+tt.query, nil)
+		c.Request.Header.Set("Authorization", "Bearer s")
+
+		f, code, err := openLogFollower(c)
+		if err != nil {
+			t.Fatalf("%s : %d %v", tt.query, code, err)
+		}
+		f.Close()
+
+		var got []string
+		for _, l := range f.history {
+			got = append(got, l.Text)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s : history %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s : history %v, want %v", tt.query, got, tt.want)
+				break
+			}
+		}
+	}
+}
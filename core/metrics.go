@@ -0,0 +1,233 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//MetricsConfig - Prometheus metrics endpoint
+type MetricsConfig struct {
+	ADDRESS string
+	AUTH    bool
+	ENABLED bool
+	PATH    string
+}
+
+//latencyBuckets - Upper bounds in seconds of request latency histogram
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+//moduleStates - States exported as module state gauge
+var moduleStates = []ModuleState{Unknown, Loading, Downloaded, Online, Stopped, Error, Failed, LimitExceeded}
+
+//bindingMetrics - Requests served through a module binding
+type bindingMetrics struct {
+	buckets        []uint64
+	codes          map[int]uint64
+	count          uint64
+	sum            float64
+	upstreamErrors uint64
+}
+
+//requestMetrics - Requests metrics by module and route
+type requestMetrics struct {
+	mux      sync.Mutex
+	bindings map[[2]string]*bindingMetrics
+}
+
+func (rm *requestMetrics) binding(module string, route string) *bindingMetrics {
+	if rm.bindings == nil {
+		rm.bindings = map[[2]string]*bindingMetrics{}
+	}
+	k := [2]string{module, route}
+	b, ok := rm.bindings[k]
+	if !ok {
+		b = &bindingMetrics{buckets: make([]uint64, len(latencyBuckets)), codes: map[int]uint64{}}
+		rm.bindings[k] = b
+	}
+	return b
+}
+
+//Observe - Count a request served by module route
+func (rm *requestMetrics) Observe(module string, route string, code int, d time.Duration) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+
+	b := rm.binding(module, route)
+	b.codes[code]++
+	b.count++
+	b.sum += d.Seconds()
+	for i := range latencyBuckets {
+		if d.Seconds() <= latencyBuckets[i] {
+			b.buckets[i]++
+		}
+	}
+}
+
+//UpstreamError - Count a request module did not answer
+func (rm *requestMetrics) UpstreamError(module string, route string) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+	rm.binding(module, route).upstreamErrors++
+}
+
+//write - Write requests metrics in Prometheus text format
+func (rm *requestMetrics) write(w io.Writer) {
+	rm.mux.Lock()
+	defer rm.mux.Unlock()
+
+	var keys [][2]string
+	for k := range rm.bindings {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0]+" "+keys[i][1] < keys[j][0]+" "+keys[j][1]
+	})
+
+	fmt.Fprintln(w, "# HELP woxy_requests_total Requests served through module bindings.")
+	fmt.Fprintln(w, "# TYPE woxy_requests_total counter")
+	for _, k := range keys {
+		b := rm.bindings[k]
+		var codes []int
+		for c := range b.codes {
+			codes = append(codes, c)
+		}
+		sort.Ints(codes)
+		for _, c := range codes {
+			fmt.Fprintf(w, "woxy_requests_total{module=%q,route=%q,code=\"%d\"} %d\n", k[0], k[1], c, b.codes[c])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP woxy_request_duration_seconds Latency of requests served through module bindings.")
+	fmt.Fprintln(w, "# TYPE woxy_request_duration_seconds histogram")
+	for _, k := range keys {
+		b := rm.bindings[k]
+		for i := range latencyBuckets {
+			fmt.Fprintf(w, "woxy_request_duration_seconds_bucket{module=%q,route=%q,le=\"%s\"} %d\n", k[0], k[1], strconv.FormatFloat(latencyBuckets[i], 'g', -1, 64), b.buckets[i])
+		}
+		fmt.Fprintf(w, "woxy_request_duration_seconds_bucket{module=%q,route=%q,le=\"+Inf\"} %d\n", k[0], k[1], b.count)
+		fmt.Fprintf(w, "woxy_request_duration_seconds_sum{module=%q,route=%q} %g\n", k[0], k[1], b.sum)
+		fmt.Fprintf(w, "woxy_request_duration_seconds_count{module=%q,route=%q} %d\n", k[0], k[1], b.count)
+	}
+
+	fmt.Fprintln(w, "# HELP woxy_upstream_errors_total Requests modules did not answer.")
+	fmt.Fprintln(w, "# TYPE woxy_upstream_errors_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "woxy_upstream_errors_total{module=%q,route=%q} %d\n", k[0], k[1], rm.bindings[k].upstreamErrors)
+	}
+}
+
+//writeMetrics - Write core and modules metrics in Prometheus text format
+func writeMetrics(w io.Writer) {
+	GetManager().GetMetrics().write(w)
+
+	names := GetManager().GetConfig().moduleNames()
+	mods := GetManager().GetModules()
+
+	fmt.Fprintln(w, "# HELP woxy_module_state Current module state.")
+	fmt.Fprintln(w, "# TYPE woxy_module_state gauge")
+	for _, n := range names {
+		for _, s := range moduleStates {
+			v := 0
+			if mods[n].STATE == s {
+				v = 1
+			}
+			fmt.Fprintf(w, "woxy_module_state{module=%q,state=%q} %d\n", n, string(s), v)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP woxy_module_ready Module readiness probe status.")
+	fmt.Fprintln(w, "# TYPE woxy_module_ready gauge")
+	for _, n := range names {
+		m := mods[n]
+		fmt.Fprintf(w, "woxy_module_ready{module=%q} %d\n", n, boolMetric(m.ready()))
+	}
+
+	fmt.Fprintln(w, "# HELP woxy_module_starts_total Module process starts.")
+	fmt.Fprintln(w, "# TYPE woxy_module_starts_total counter")
+	for _, n := range names {
+		fmt.Fprintf(w, "woxy_module_starts_total{module=%q} %d\n", n, GetManager().GetLogStore(n).Instances())
+	}
+
+	fmt.Fprintln(w, "# HELP woxy_module_restarts_total Module restarts after liveness probe failure.")
+	fmt.Fprintln(w, "# TYPE woxy_module_restarts_total counter")
+	for _, n := range names {
+		fmt.Fprintf(w, "woxy_module_restarts_total{module=%q} %d\n", n, GetManager().GetHealth(n).RESTARTS)
+	}
+
+	samples := map[string]PerfSample{}
+	for _, n := range names {
+		if s, ok := GetManager().GetPerfStore().Last(n); ok {
+			samples[n] = s
+		}
+	}
+	sampled := func(name string, typ string, help string, value func(PerfSample) string) {
+		fmt.Fprintln(w, "# HELP "+name+" "+help)
+		fmt.Fprintln(w, "# TYPE "+name+" "+typ)
+		for _, n := range names {
+			if s, ok := samples[n]; ok {
+				fmt.Fprintf(w, "%s{module=%q} %s\n", name, n, value(s))
+			}
+		}
+	}
+	sampled("woxy_module_cpu_percent", "gauge", "Module process CPU usage.", func(s PerfSample) string { return strconv.FormatFloat(s.CPU, 'g', -1, 64) })
+	sampled("woxy_module_resident_memory_bytes", "gauge", "Module process resident memory.", func(s PerfSample) string { return strconv.FormatUint(s.RSS, 10) })
+	sampled("woxy_module_threads", "gauge", "Module process threads.", func(s PerfSample) string { return strconv.Itoa(int(s.THREADS)) })
+	sampled("woxy_module_open_fds", "gauge", "Module process open file descriptors.", func(s PerfSample) string { return strconv.Itoa(int(s.FDS)) })
+	sampled("woxy_module_read_bytes_total", "counter", "Module process bytes read.", func(s PerfSample) string { return strconv.FormatUint(s.READ_BYTES, 10) })
+	sampled("woxy_module_write_bytes_total", "counter", "Module process bytes written.", func(s PerfSample) string { return strconv.FormatUint(s.WRITE_BYTES, 10) })
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	fmt.Fprintln(w, "# HELP go_goroutines Number of goroutines of go-woxy core.")
+	fmt.Fprintln(w, "# TYPE go_goroutines gauge")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+	fmt.Fprintln(w, "# HELP go_memstats_alloc_bytes Bytes allocated by go-woxy core.")
+	fmt.Fprintln(w, "# TYPE go_memstats_alloc_bytes gauge")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes %d\n", ms.Alloc)
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//metrics - Serve Prometheus metrics, server secret is required when AUTH is set
+func metrics(w http.ResponseWriter, r *http.Request) {
+	if GetManager().GetConfig().METRICS.AUTH && !hashMatchSecretHash(requestSecret(r)) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w)
+}
+
+//serveMetrics - Serve metrics on main router, or on their own address when set
+func (c *Config) serveMetrics(router *gin.Engine) {
+	m := c.METRICS
+	if !m.ENABLED {
+		return
+	}
+	if m.ADDRESS == "" {
+		router.GET(m.PATH, gin.WrapF(metrics))
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(m.PATH, metrics)
+	fmt.Println("GO-WOXY Core - Serving metrics at http://" + m.ADDRESS + m.PATH)
+	//METRICS LISTENER FAILING DOES NOT STOP CORE NOR ITS MODULES
+	go func() {
+		log.Println("GO-WOXY Core - Error metrics ListenAndServe, metrics not served on", m.ADDRESS, ":", http.ListenAndServe(m.ADDRESS, mux))
+	}()
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+//sampleLine - Prometheus text format sample : name, optional labels and value
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[a-zA-Z_][a-zA-Z0-9_]*="[^"]*"(,[a-zA-Z_][a-zA-Z0-9_]*="[^"]*")*\})? ([-+0-9.eE]+|\+Inf|NaN)$`)
+
+func TestRequestMetrics(t *testing.T) {
+	var rm requestMetrics
+	rm.Observe("m", "/m", 200, 3*time.Millisecond)
+	rm.Observe("m", "/m", 200, 300*time.Millisecond)
+	rm.Observe("m", "/m", 502, 20*time.Second)
+	rm.UpstreamError("m", "/m")
+	rm.Observe("a", "/", 404, time.Millisecond)
+
+	var b bytes.Buffer
+	rm.write(&b)
+	out := b.String()
+
+	tests := []string{
+		`woxy_requests_total{module="a",route="/",code="404"} 1`,
+		`woxy_requests_total{module="m",route="/m",code="200"} 2`,
+		`woxy_requests_total{module="m",route="/m",code="502"} 1`,
+		`woxy_request_duration_seconds_bucket{module="m",route="/m",le="0.005"} 1`,
+		`woxy_request_duration_seconds_bucket{module="m",route="/m",le="0.25"} 1`,
+		`woxy_request_duration_seconds_bucket{module="m",route="/m",le="0.5"} 2`,
+		`woxy_request_duration_seconds_bucket{module="m",route="/m",le="10"} 2`,
+		`woxy_request_duration_seconds_bucket{module="m",route="/m",le="+Inf"} 3`,
+		`woxy_request_duration_seconds_count{module="m",route="/m"} 3`,
+		`woxy_upstream_errors_total{module="m",route="/m"} 1`,
+		`woxy_upstream_errors_total{module="a",route="/"} 0`,
+	}
+	for _, want := range tests {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	if strings.Index(out, `module="a"`) > strings.Index(out, `module="m"`) {
+		t.Errorf("metrics not sorted by module")
+	}
+}
+
+func TestMetricsFormat(t *testing.T) {
+	GetManager().SetState(&Config{
+		LOG:     LogConfig{BUFFER: 100},
+		MODULES: map[string]ModuleConfig{"m": {NAME: "m", STATE: Online}},
+		METRICS: MetricsConfig{ENABLED: true, PATH: "/metrics"},
+	})
+	GetManager().GetMetrics().Observe("m", "/m", 200, time.Millisecond)
+
+	w := httptest.NewRecorder()
+	metrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %s", ct)
+	}
+
+	//EVERY SAMPLE BELONGS TO A FAMILY DECLARED BY HELP AND TYPE, FAMILIES DECLARED ONCE
+	types := map[string]string{}
+	helps := map[string]bool{}
+	for _, l := range strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n") {
+		f := strings.Fields(l)
+		switch {
+		case strings.HasPrefix(l, "# HELP "):
+			if helps[f[2]] {
+				t.Errorf("HELP %s declared twice", f[2])
+			}
+			helps[f[2]] = true
+		case strings.HasPrefix(l, "# TYPE "):
+			if !helps[f[2]] {
+				t.Errorf("TYPE %s before its HELP", f[2])
+			}
+			if _, ok := types[f[2]]; ok {
+				t.Errorf("TYPE %s declared twice", f[2])
+			}
+			types[f[2]] = f[3]
+		default:
+			m := sampleLine.FindStringSubmatch(l)
+			if m == nil {
+				t.Errorf("invalid sample line %q", l)
+				continue
+			}
+			family := m[1]
+			if _, ok := types[family]; !ok && types[strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(family, "_bucket"), "_sum"), "_count")] != "histogram" {
+				t.Errorf("sample %s without TYPE", family)
+			}
+		}
+	}
+
+	for name, typ := range map[string]string{
+		"woxy_requests_total":           "counter",
+		"woxy_request_duration_seconds": "histogram",
+		"woxy_module_state":             "gauge",
+		"woxy_module_ready":             "gauge",
+		"woxy_module_restarts_total":    "counter",
+		"go_goroutines":                 "gauge",
+	} {
+		if types[name] != typ {
+			t.Errorf("%s type = %q, want %q", name, types[name], typ)
+		}
+	}
+	if !strings.Contains(w.Body.String(), `woxy_module_state{module="m",state="ONLINE"} 1`) {
+		t.Errorf("module state missing")
+	}
+}
+
+func TestMetricsListenError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	//ADDRESS ALREADY IN USE, CORE MUST KEEP RUNNING
+	c := &Config{METRICS: MetricsConfig{ENABLED: true, PATH: "/metrics", ADDRESS: l.Addr().String()}}
+	c.serveMetrics(gin.New())
+	time.Sleep(200 * time.Millisecond)
+}
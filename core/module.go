@@ -1,49 +1,48 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
-	"os/exec"
 	"reflect"
-	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/Wariie/go-woxy/com"
 	auth "github.com/abbot/go-http-auth"
 	"github.com/gin-gonic/gin"
-	"github.com/shirou/gopsutil/process"
 )
 
-//Download - Download module from repository ( git clone )
-func (mc *ModuleConfig) Download() {
-
-	if mc.STATE != Online {
-		var listArgs []string
-		var action string
-
-		wd := "./mods/"
-		if _, err := os.Stat(wd + mc.NAME + "/"); os.IsNotExist(err) {
-			listArgs = []string{"clone", mc.EXE.SRC}
-			action = "Downloaded"
-		} else {
-			listArgs = []string{"pull"}
-			action = "Update"
-			wd += mc.NAME + "/"
+//Download - Download module from its source ( git repository, archive or local path )
+func (mc *ModuleConfig) Download(ctx context.Context) {
+
+	if !mc.STATE.running() {
+		var err error
+		switch mc.sourceType() {
+		case archiveSource:
+			err = mc.downloadArchive(ctx)
+		case localSource:
+			err = mc.copyLocal()
+		case gitSource:
+			err = mc.downloadGit(ctx)
+		default:
+			err = errors.New("unknown source " + mc.EXE.SRC)
 		}
 
-		cmd := exec.Command("git", listArgs...)
-		cmd.Dir = wd
-		out, err := cmd.CombinedOutput()
-		fmt.Println(action, " mod : ", mc, " - ", string(out), " ", err)
+		if err != nil {
+			log.Println("GO-WOXY Core - Error downloading mod", mc.NAME, ":", err)
+			mc.STATE = Error
+			return
+		}
 
-		mc.EXE.BIN = "./mods/" + mc.NAME + "/"
 		mc.STATE = Downloaded
 	} else {
 		log.Println("Error - Trying to download/update module while running\nStop it before")
@@ -51,25 +50,64 @@ func (mc *ModuleConfig) Download() {
 }
 
 //GetLog - GetLog from Module
-func (mc *ModuleConfig) GetLog() string {
+func (mc *ModuleConfig) GetLog(q LogQuery) string {
+	var b strings.Builder
+	for _, l := range GetManager().GetLogStore(mc.NAME).Query(q) {
+		b.WriteString(l.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
 
-	file, err := os.Open("./mods/" + mc.NAME + "/log.log")
-	if err != nil {
-		log.Fatalln("failed reading file :", err)
+//moduleSummary - Module state and binding shown to operators
+type moduleSummary struct {
+	ADDRESS  string
+	COMMIT   string
+	HEALTH   HealthStatus
+	LABELS   map[string]string
+	NAME     string
+	PINNED   string
+	PORT     string
+	PROTOCOL string
+	READY    bool
+	ROUTES   []Route
+	SOURCE   string
+	STATE    ModuleState
+	TYPES    string
+}
+
+//commandSpec - Get spec sent by module for its custom command
+func (mc *ModuleConfig) commandSpec(name string) (com.CommandSpec, bool) {
+	for _, s := range mc.COMMAND_SPECS {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return com.CommandSpec{}, false
+}
+
+//summary - Get module summary
+func (mc *ModuleConfig) summary() moduleSummary {
+	return moduleSummary{
+		ADDRESS:  mc.BINDING.ADDRESS,
+		COMMIT:   mc.COMMIT,
+		HEALTH:   GetManager().GetHealth(mc.NAME),
+		LABELS:   mc.LABELS,
+		NAME:     mc.NAME,
+		PINNED:   mc.PINNED_COMMIT,
+		PORT:     mc.port(),
+		PROTOCOL: mc.BINDING.PROTOCOL,
+		READY:    mc.ready(),
+		ROUTES:   mc.BINDING.PATH,
+		SOURCE:   mc.EXE.SRC,
+		STATE:    mc.STATE,
+		TYPES:    mc.TYPES,
 	}
-	b, err := ioutil.ReadAll(file)
-	return string(b)
 }
 
 //GetPerf - GetPerf from Module
-func (mc *ModuleConfig) GetPerf() (float64, float32) {
-	p, err := process.NewProcess(int32(mc.pid))
-	ram, err := p.MemoryPercent()
-	cpu, err := p.Percent(0)
-	name, err := p.Name()
-	log.Println("PERF :", name, err)
-
-	return cpu, ram
+func (mc *ModuleConfig) GetPerf() (PerfSample, error) {
+	return GetManager().GetPerfStore().sample(mc)
 }
 
 //GetServer - Get Module Server configuration
@@ -77,7 +115,7 @@ func (mc *ModuleConfig) GetServer(path string) com.Server {
 	if path == "" {
 		path = mc.BINDING.PATH[0].FROM
 	}
-	return com.Server{IP: mc.BINDING.ADDRESS, Path: path, Port: mc.BINDING.PORT, Protocol: mc.BINDING.PROTOCOL}
+	return com.Server{IP: mc.BINDING.ADDRESS, Path: path, Port: mc.port(), Protocol: mc.BINDING.PROTOCOL}
 }
 
 //HookAll - Create all binding between module config address and gin server
@@ -125,41 +163,118 @@ func (mc *ModuleConfig) Hook(router *gin.Engine, r Route, typeR string) error {
 }
 
 //Setup - Setup module from config
-func (mc *ModuleConfig) Setup(router *gin.Engine, hook bool) error {
+func (mc *ModuleConfig) Setup(ctx context.Context, router *gin.Engine, hook bool) error {
 	fmt.Println("GO-WOXY Core - Setup mod : ", mc)
+	var err error
 	if !mc.EXE.REMOTE && !reflect.DeepEqual(mc.EXE, ModuleExecConfig{}) {
-		if strings.Contains(mc.EXE.SRC, "http") || strings.Contains(mc.EXE.SRC, "git@") {
-			mc.Download()
+		if mc.sourceType() != "" {
+			mc.Download(ctx)
 		}
-		mc.copySecret()
-		go mc.Start()
-	} // ELSE NO BUILD
+		if mc.STATE == Error {
+			//KEEP HOOKING SO MODULE ROUTES ANSWER WITH ERROR PAGE
+			err = errors.New("Error downloading mod " + mc.NAME)
+		} else {
+			mc.copySecret()
+			//MODULE STARTS ON ITS OWN COPY, CALLER SAVES mc WHILE IT RUNS
+			mc.STATE = Loading
+			m := *mc
+			go m.Start()
+		}
+	} else {
+		//NO BUILD, PROBES RUN AGAINST MODULE STARTED ELSEWHERE
+		mc.watchExternalHealth()
+	}
 
 	if hook {
-		return mc.HookAll(router)
+		if e := mc.HookAll(router); e != nil {
+			return e
+		}
 	}
-	return nil
+	return err
+}
+
+//setState - Set module state and save it, other saved fields are kept
+func (mc *ModuleConfig) setState(state ModuleState) {
+	mc.STATE = state
+	GetManager().UpdateModule(mc.NAME, func(m *ModuleConfig) {
+		m.STATE = state
+	})
 }
 
 //Start - Start module with config args and auto args
 func (mc *ModuleConfig) Start() {
 	mc.STATE = Loading
+	if err := mc.waitDependencies(); err != nil {
+		log.Println("GO-WOXY Core - Error starting mod", mc.NAME, ":", err)
+		mc.setState(Error)
+		return
+	}
 
-	var platformParam []string
-	if runtime.GOOS == "windows" {
-		platformParam = []string{"cmd", "/c ", "go", "run", mc.EXE.MAIN, "1>", "log.log", "2>&1"}
-	} else {
-		platformParam = []string{"/bin/sh", "-c", "go run " + mc.EXE.MAIN + " > log.log 2>&1"}
+	env, masks, err := mc.environment()
+	var args []string
+	if err == nil {
+		args, err = mc.arguments()
+	}
+	var port string
+	if err == nil {
+		port, err = mc.reservePort()
 	}
+	if err != nil {
+		log.Println("GO-WOXY Core - Error starting mod", mc.NAME, ":", err)
+		mc.setState(Error)
+		return
+	}
+	defer GetManager().GetPortAllocator().Release(mc.NAME)
+	env = append(env, "WOXY_PORT="+port)
+	env = append(env, traceEnvironment(GetManager().GetConfig().TRACING)...)
+
+	ls := GetManager().GetLogStore(mc.NAME)
+	ls.SetMasks(masks)
+	instance := ls.NewInstance()
+	stdout := ls.Writer(instance, "stdout")
+	stderr := ls.Writer(instance, "stderr")
 
 	fmt.Println("GO-WOXY Core - Starting mod : ", mc)
-	cmd := exec.Command(platformParam[0], platformParam[1:]...)
-	cmd.Dir = mc.EXE.BIN
-	output, err := cmd.Output()
+	//BINARY IS BUILT FIRST SO LIMITS ONLY APPLY TO MODULE, NOT TO go TOOLCHAIN
+	bin, err := mc.compile(stderr)
+	var limits *processLimits
+	if err == nil {
+		limits, err = mc.prepareLimits()
+	}
+	if err != nil {
+		stdout.Close()
+		stderr.Close()
+		log.Println("GO-WOXY Core - Error starting mod", mc.NAME, ":", err)
+		mc.setState(Error)
+		return
+	}
+
+	cmd, err := mc.startProcess(limits, bin, args, env, stdout, stderr)
+	if err == nil {
+		p := &moduleProcess{done: make(chan struct{}), process: cmd.Process}
+		GetManager().SetProcess(mc.NAME, p)
+		mc.watchHealth(p)
+		limits.watch(mc, instance)
+		err = cmd.Wait()
+		close(p.done)
+		//KILL PROCESSES LEFT IN MODULE GROUP
+		signalGroup(p.process, syscall.SIGKILL)
+		limits.release()
+		if GetManager().GetProcess(mc.NAME) == p {
+			GetManager().SetHealth(mc.NAME, func(s *HealthStatus) {
+				s.LIVE, s.READY = false, false
+			})
+		}
+		GetManager().RemoveProcess(mc.NAME, p)
+	} else {
+		limits.release()
+	}
+	stdout.Close()
+	stderr.Close()
 	if err != nil {
 		log.Println("GO-WOXY Core - Error:", err)
 	}
-	log.Println("GO-WOXY Core - Output :", string(output), err)
+	log.Println("GO-WOXY Core - Mod", mc.NAME, "instance", instance, "exited :", err)
 }
 
 func (mc *ModuleConfig) copySecret() {
@@ -169,7 +284,7 @@ func (mc *ModuleConfig) copySecret() {
 	}
 	defer source.Close()
 
-	destination, err := os.Create(mc.EXE.BIN + "/.secret")
+	destination, err := os.OpenFile(mc.EXE.BIN+"/.secret", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
 		log.Println("GO-WOXY Core - Error creating mod secret file")
 	}
@@ -218,10 +333,31 @@ func NewSingleHostReverseProxy(target *url.URL) *httputil.ReverseProxy {
 //ReverseProxy - reverse proxy for mod
 func ReverseProxy(modName string, r Route) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		mod := GetManager().config.MODULES[modName]
+		mod := GetManager().GetModule(modName)
+		start := time.Now()
+		c.Set(upstreamKey, modName)
+
+		//PROXY HOP SPAN, PARENT OF MODULE SPANS
+		span := com.StartSpan("proxy "+modName, com.SpanServer, c.GetHeader(com.TraceHeader))
+		span.Set("http.method", c.Request.Method)
+		span.Set("http.route", r.FROM)
+		span.Set("woxy.module", modName)
+		span.Set("http.request_id", c.GetString(requestIDKey))
+		c.Request.Header.Set(com.TraceHeader, span.Context().Traceparent())
+		var proxyErr error
+
+		defer func() {
+			status := c.Writer.Status()
+			GetManager().GetMetrics().Observe(modName, r.FROM, status, time.Since(start))
+			span.Set("http.status_code", strconv.Itoa(status))
+			if proxyErr == nil && status >= 500 {
+				proxyErr = errors.New(http.StatusText(status))
+			}
+			span.Finish(proxyErr)
+		}()
 
-		//CHECK IF MODULE IS ONLINE
-		if mod.STATE == Online {
+		//CHECK IF MODULE IS ONLINE ( A MODULE OVER ITS LIMITS MAY STILL ANSWER ) AND READY
+		if mod.STATE.running() && mod.ready() {
 			//IF ROOT IS PRESENT REDIRECT TO IT
 			if strings.Contains(mod.TYPES, "bind") && mod.BINDING.ROOT != "" {
 				c.File(mod.BINDING.ROOT)
@@ -229,19 +365,36 @@ func ReverseProxy(modName string, r Route) gin.HandlerFunc {
 			} else if strings.Contains(mod.TYPES, "web") {
 				//ELSE IF BINDING IS TYPE **WEB**
 				//REVERSE PROXY TO IT
-				url, err := url.Parse(mod.BINDING.PROTOCOL + "://" + mod.BINDING.ADDRESS + ":" + mod.BINDING.PORT + r.TO)
+				url, err := url.Parse(mod.BINDING.PROTOCOL + "://" + mod.BINDING.ADDRESS + ":" + mod.port() + r.TO)
 				if err != nil {
-					log.Println(err)
+					log.Println("GO-WOXY Core - Error parsing mod", modName, "url :", err, "request_id="+c.GetString(requestIDKey))
 				}
 				proxy := NewSingleHostReverseProxy(url)
+				//REQUEST ID IS ALREADY ECHOED BY CORE
+				proxy.ModifyResponse = func(resp *http.Response) error {
+					resp.Header.Del(com.RequestIDHeader)
+					return nil
+				}
+				proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+					log.Println("GO-WOXY Core - Error proxying to mod", modName, ":", err, "request_id="+c.GetString(requestIDKey))
+					GetManager().GetMetrics().UpstreamError(modName, r.FROM)
+					proxyErr = err
+					w.WriteHeader(http.StatusBadGateway)
+				}
+				upstreamStart := time.Now()
 				proxy.ServeHTTP(c.Writer, c.Request)
+				c.Set(upstreamLatencyKey, time.Since(upstreamStart))
 			}
 			//TODO HANDLE MORE STATES
 		} else {
 			title := ""
 			code := 500
 			message := ""
-			if mod.STATE == Loading || mod.STATE == Downloaded {
+			if mod.STATE.running() {
+				title = "Not ready"
+				code += 3
+				message = "Module is not ready yet ..."
+			} else if mod.STATE == Loading || mod.STATE == Downloaded {
 				title = "Loading"
 				code += 3
 				message = "Module is loading ..."
@@ -254,9 +407,10 @@ func ReverseProxy(modName string, r Route) gin.HandlerFunc {
 				message = "Error"
 			}
 			c.HTML(code, "loading.html", gin.H{
-				"title":   title,
-				"code":    code,
-				"message": message,
+				"title":      title,
+				"code":       code,
+				"message":    message,
+				"request_id": c.GetString(requestIDKey),
 			})
 		}
 	}
@@ -276,46 +430,79 @@ func singleJoiningSlash(a, b string) string {
 
 /*Config - Global configuration */
 type Config struct {
-	MODULES map[string]ModuleConfig
-	NAME    string
-	SERVER  ServerConfig
-	VERSION int
-	MOTD    string
-	SECRET  string
+	ACCESS_LOG AccessLogConfig
+	DASHBOARD  DashboardConfig
+	GIT        GitAuthConfig
+	JOBS       JobConfig
+	LOG        LogConfig
+	METRICS    MetricsConfig
+	MODULES    map[string]ModuleConfig
+	NAME       string
+	PERF       PerfConfig
+	PORTS      PortRangeConfig
+	SERVER     ServerConfig
+	VERSION    int
+	MOTD       string
+	SECRET     string
+	TRACING    TracingConfig
+	WEBHOOK    WebhookConfig
+	order      []string
 }
 
 /*ModuleConfig - Module configuration */
 type ModuleConfig struct {
-	AUTH     ModuleAuthConfig
-	BINDING  ServerConfig
-	COMMANDS []string
-	EXE      ModuleExecConfig
-	NAME     string
-	pid      int
-	PK       string
-	STATE    ModuleState
-	TYPES    string
-	VERSION  int
+	AUTH             ModuleAuthConfig
+	BINDING          ServerConfig
+	COMMAND_SPECS    []com.CommandSpec
+	COMMANDS         []string
+	COMMIT           string
+	DEPENDS_ON       []Dependency
+	EXE              ModuleExecConfig
+	HEALTH           HealthConfig
+	LABELS           map[string]string
+	NAME             string
+	pid              int
+	PINNED_COMMIT    string
+	PK               string
+	PREVIOUS_COMMITS []string
+	STATE            ModuleState
+	TYPES            string
+	VERSION          int
 }
 
 /*ModuleExecConfig - Module exec file informations */
 type ModuleExecConfig struct {
-	BIN        string
-	MAIN       string
-	SRC        string
-	SUPERVISED bool
-	REMOTE     bool
+	ARGS        []string
+	BIN         string
+	ENV         map[string]string
+	GIT         GitAuthConfig
+	GROUP       string
+	INHERIT_ENV bool
+	ISOLATION   IsolationConfig
+	LIMITS      LimitsConfig
+	MAIN        string
+	PUBLIC_KEY  string
+	REF         string
+	SECRETS     SecretMap
+	SHA256      string
+	SIGNATURE   string
+	SRC         string
+	STOP        StopConfig
+	SUPERVISED  bool
+	REMOTE      bool
+	USER        string
 }
 
 /*ServerConfig - Server configuration*/
 type ServerConfig struct {
-	ADDRESS  string
-	PATH     []Route
-	PORT     string
-	PROTOCOL string
-	ROOT     string
-	CERT     string
-	CERT_KEY string
+	ACCESS_LOG BindingAccessLog
+	ADDRESS    string
+	PATH       []Route
+	PORT       string
+	PROTOCOL   string
+	ROOT       string
+	CERT       string
+	CERT_KEY   string
 }
 
 /*ModuleAuthConfig - Auth configuration*/
@@ -341,4 +528,11 @@ const (
 	Downloaded ModuleState = "DOWNLOADED"
 	Error      ModuleState = "ERROR"
 	Failed     ModuleState = "FAILED"
+	//LimitExceeded - Module hit one of its resources limits
+	LimitExceeded ModuleState = "LIMIT_EXCEEDED"
 )
+
+//running - Check if module in state is running, a module over its limits still runs
+func (s ModuleState) running() bool {
+	return s == Online || s == LimitExceeded
+}
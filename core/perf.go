@@ -0,0 +1,265 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/process"
+)
+
+//PerfConfig - Module performance sampling configuration
+type PerfConfig struct {
+	INTERVAL time.Duration
+}
+
+//PerfSample - Module process resources usage at a time
+type PerfSample struct {
+	CPU         float64
+	FDS         int32
+	READ_BYTES  uint64
+	RSS         uint64
+	THREADS     int32
+	TIME        time.Time
+	WRITE_BYTES uint64
+}
+
+//PerfSeries - Module samples over a time range, at STEP resolution
+type PerfSeries struct {
+	MODULE  string
+	SAMPLES []PerfSample
+	STEP    string
+}
+
+//perfTier - Samples merged by step and kept for retention, raw samples when step is 0
+type perfTier struct {
+	step      time.Duration
+	retention time.Duration
+	samples   []PerfSample
+	merged    int
+}
+
+//perfStore - Modules samples time-series
+type perfStore struct {
+	mux    sync.Mutex
+	series map[string][]*perfTier
+	procs  map[string]*process.Process
+	errors map[string]string
+}
+
+//perfTiers - Raw samples for an hour, one per minute for a day and one per 15 minutes for a week
+var perfTiers = []perfTier{{0, time.Hour, nil, 0}, {time.Minute, 24 * time.Hour, nil, 0}, {15 * time.Minute, 7 * 24 * time.Hour, nil, 0}}
+
+//merge - Average CPU and RSS, keep max threads and FDs and last I/O counters
+func (s PerfSample) merge(o PerfSample, n int) PerfSample {
+	s.CPU = (s.CPU*float64(n) + o.CPU) / float64(n+1)
+	s.RSS = (s.RSS*uint64(n) + o.RSS) / uint64(n+1)
+	if o.THREADS > s.THREADS {
+		s.THREADS = o.THREADS
+	}
+	if o.FDS > s.FDS {
+		s.FDS = o.FDS
+	}
+	s.READ_BYTES, s.WRITE_BYTES = o.READ_BYTES, o.WRITE_BYTES
+	return s
+}
+
+func (t *perfTier) add(s PerfSample) {
+	if t.step > 0 {
+		s.TIME = s.TIME.Truncate(t.step)
+		if l := len(t.samples); l > 0 && t.samples[l-1].TIME.Equal(s.TIME) {
+			t.samples[l-1] = t.samples[l-1].merge(s, t.merged)
+			t.merged++
+			return
+		}
+	}
+	t.samples = append(t.samples, s)
+	t.merged = 1
+
+	limit := s.TIME.Add(-t.retention)
+	i := 0
+	for i < len(t.samples) && t.samples[i].TIME.Before(limit) {
+		i++
+	}
+	if i > 0 {
+		t.samples = append([]PerfSample(nil), t.samples[i:]...)
+	}
+}
+
+func newPerfStore() *perfStore {
+	return &perfStore{series: map[string][]*perfTier{}, procs: map[string]*process.Process{}, errors: map[string]string{}}
+}
+
+//sample - Measure module process resources usage
+func (ps *perfStore) sample(mc *ModuleConfig) (PerfSample, error) {
+	s := PerfSample{TIME: time.Now()}
+	if mc.pid == 0 {
+		return s, errors.New("mod " + mc.NAME + " has no known process")
+	}
+
+	//KEEP PROCESS BETWEEN SAMPLES SO CPU IS MEASURED SINCE PREVIOUS ONE
+	ps.mux.Lock()
+	p, ok := ps.procs[mc.NAME]
+	ps.mux.Unlock()
+	if !ok || p.Pid != int32(mc.pid) {
+		var err error
+		if p, err = process.NewProcess(int32(mc.pid)); err != nil {
+			return s, err
+		}
+		ps.mux.Lock()
+		ps.procs[mc.NAME] = p
+		ps.mux.Unlock()
+	}
+
+	var err error
+	if s.CPU, err = p.Percent(0); err != nil {
+		return s, err
+	}
+	mem, err := p.MemoryInfo()
+	if err != nil {
+		return s, err
+	}
+	s.RSS = mem.RSS
+	if s.THREADS, err = p.NumThreads(); err != nil {
+		return s, err
+	}
+	if s.FDS, err = p.NumFDs(); err != nil {
+		return s, err
+	}
+	ioc, err := p.IOCounters()
+	if err != nil {
+		return s, err
+	}
+	s.READ_BYTES, s.WRITE_BYTES = ioc.ReadBytes, ioc.WriteBytes
+	return s, nil
+}
+
+//Add - Add sample to module series
+func (ps *perfStore) Add(name string, s PerfSample) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	tiers, ok := ps.series[name]
+	if !ok {
+		for i := range perfTiers {
+			t := perfTiers[i]
+			tiers = append(tiers, &t)
+		}
+		ps.series[name] = tiers
+	}
+	for _, t := range tiers {
+		t.add(s)
+	}
+}
+
+//Last - Get last raw sample of module
+func (ps *perfStore) Last(name string) (PerfSample, bool) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	tiers := ps.series[name]
+	if len(tiers) == 0 || len(tiers[0].samples) == 0 {
+		return PerfSample{}, false
+	}
+	return tiers[0].samples[len(tiers[0].samples)-1], true
+}
+
+//Query - Get module samples between from and to, from the finest tier still holding from
+func (ps *perfStore) Query(name string, from time.Time, to time.Time) PerfSeries {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	res := PerfSeries{MODULE: name, SAMPLES: []PerfSample{}}
+	tiers := ps.series[name]
+	if len(tiers) == 0 {
+		return res
+	}
+
+	t := tiers[len(tiers)-1]
+	for _, c := range tiers {
+		if time.Since(from) <= c.retention {
+			t = c
+			break
+		}
+	}
+
+	//RAW SAMPLES ARE TAKEN AT SAMPLING INTERVAL
+	res.STEP = t.step.String()
+	if t.step == 0 {
+		res.STEP = GetManager().GetConfig().PERF.INTERVAL.String()
+	}
+	for _, s := range t.samples {
+		if !s.TIME.Before(from.Truncate(t.step)) && !s.TIME.After(to) {
+			res.SAMPLES = append(res.SAMPLES, s)
+		}
+	}
+	return res
+}
+
+//failed - Log sampling error of module, only when it changes
+func (ps *perfStore) failed(name string, err error) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if msg != ps.errors[name] && msg != "" {
+		log.Println("GO-WOXY Core - Error sampling performance of mod", name, ":", msg)
+	}
+	ps.errors[name] = msg
+}
+
+//Sample - Sample resources usage of running modules at interval
+func (s *Supervisor) Sample(interval time.Duration) {
+	ps := GetManager().GetPerfStore()
+	for {
+		time.Sleep(interval)
+		for _, name := range GetManager().GetConfig().moduleNames() {
+			mc := GetManager().GetModule(name)
+			if mc.pid == 0 || !mc.STATE.running() {
+				continue
+			}
+
+			sample, err := mc.GetPerf()
+			ps.failed(name, err)
+			if err == nil {
+				ps.Add(name, sample)
+			}
+		}
+	}
+}
+
+//parsePerfQuery - Parse "from=1h&to=2006-01-02T15:04:05Z" range, durations are relative to now (default : last hour)
+func parsePerfQuery(s string) (time.Time, time.Time, error) {
+	from, to := time.Now().Add(-time.Hour), time.Now()
+
+	v, err := url.ParseQuery(s)
+	if err != nil {
+		return from, to, err
+	}
+
+	parse := func(t string) (time.Time, error) {
+		if d, err := time.ParseDuration(t); err == nil {
+			return time.Now().Add(-d), nil
+		}
+		return time.Parse(time.RFC3339, t)
+	}
+	if t := v.Get("from"); t != "" {
+		if from, err = parse(t); err != nil {
+			return from, to, errors.New("invalid from : " + t)
+		}
+	}
+	if t := v.Get("to"); t != "" {
+		if to, err = parse(t); err != nil {
+			return from, to, errors.New("invalid to : " + t)
+		}
+	}
+	if to.Before(from) {
+		return from, to, errors.New("invalid range : to is before from")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPerfSampleMerge(t *testing.T) {
+	s := PerfSample{CPU: 10, RSS: 100, THREADS: 4, FDS: 10, READ_BYTES: 1, WRITE_BYTES: 2}
+	s = s.merge(PerfSample{CPU: 40, RSS: 400, THREADS: 2, FDS: 20, READ_BYTES: 5, WRITE_BYTES: 6}, 1)
+	s = s.merge(PerfSample{CPU: 100, RSS: 1000, THREADS: 8, FDS: 5, READ_BYTES: 9, WRITE_BYTES: 10}, 2)
+	want := PerfSample{CPU: 50, RSS: 500, THREADS: 8, FDS: 20, READ_BYTES: 9, WRITE_BYTES: 10}
+	if s != want {
+		t.Errorf("merge = %+v, want %+v", s, want)
+	}
+}
+
+func TestPerfStoreTiers(t *testing.T) {
+	GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}, PERF: PerfConfig{INTERVAL: 20 * time.Second}})
+
+	//ONE SAMPLE EVERY 20s FOR 2 DAYS, CPU IS MINUTES SINCE START
+	ps := newPerfStore()
+	now := time.Now().Truncate(15 * time.Minute)
+	start := now.Add(-48 * time.Hour)
+	for ts := start; !ts.After(now); ts = ts.Add(20 * time.Second) {
+		ps.Add("m", PerfSample{CPU: ts.Sub(start).Minutes(), RSS: 1, TIME: ts})
+	}
+
+	tests := []struct {
+		name    string
+		from    time.Duration
+		step    string
+		samples int
+	}{
+		{"raw samples for last hour", 30 * time.Minute, "20s", 91},
+		{"one per minute for last day", 3 * time.Hour, "1m0s", 181},
+		{"one per 15 minutes for last week", 30 * time.Hour, "15m0s", 121},
+		{"older than a week, only 2 days sampled", 10 * 24 * time.Hour, "15m0s", 48*4 + 1},
+	}
+	for _, tt := range tests {
+		s := ps.Query("m", now.Add(-tt.from), now)
+		if s.STEP != tt.step {
+			t.Errorf("%s : step = %s, want %s", tt.name, s.STEP, tt.step)
+		}
+		if len(s.SAMPLES) != tt.samples {
+			t.Errorf("%s : %d samples, want %d", tt.name, len(s.SAMPLES), tt.samples)
+		}
+	}
+
+	//MINUTE SAMPLE AVERAGES ITS 3 RAW SAMPLES (0, 1/3 AND 2/3 OF A MINUTE)
+	m := ps.Query("m", now.Add(-2*time.Hour), now).SAMPLES[0]
+	if want := m.TIME.Sub(start).Minutes() + 1.0/3; m.CPU < want-1e-9 || m.CPU > want+1e-9 {
+		t.Errorf("minute sample CPU = %v, want %v", m.CPU, want)
+	}
+	if last, ok := ps.Last("m"); !ok || !last.TIME.Equal(now) {
+		t.Errorf("Last = %v, %v", last.TIME, ok)
+	}
+	if s := ps.Query("other", now.Add(-time.Hour), now); len(s.SAMPLES) != 0 || s.STEP != "" {
+		t.Errorf("unknown module series = %+v", s)
+	}
+}
+
+func TestParsePerfQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		from    time.Duration
+		wantErr bool
+	}{
+		{"", time.Hour, false},
+		{"from=6h", 6 * time.Hour, false},
+		{"from=2h&to=1h", 2 * time.Hour, false},
+		{"from=2020-01-02T15:04:05Z", 0, false},
+		{"from=yesterday", 0, true},
+		{"to=x", 0, true},
+		{"%zz", 0, true},
+	}
+	for _, tt := range tests {
+		from, _, err := parsePerfQuery(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePerfQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+		} else if tt.from > 0 && time.Since(from)-tt.from > time.Second {
+			t.Errorf("parsePerfQuery(%q) from = %v ago, want %v", tt.query, time.Since(from), tt.from)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//PortRangeConfig - Range of ports allocated to modules without binding port
+type PortRangeConfig struct {
+	FROM int
+	TO   int
+}
+
+//portAllocator - Ports reserved by running modules
+type portAllocator struct {
+	mux  sync.Mutex
+	used map[string]string
+}
+
+//Reserve - Reserve port for module, failing if another module or process uses it
+func (pa *portAllocator) Reserve(module string, port string) error {
+	pa.mux.Lock()
+	defer pa.mux.Unlock()
+	return pa.reserve(module, port)
+}
+
+//Allocate - Reserve first free port of range for module, skipping ports configured for other modules
+func (pa *portAllocator) Allocate(module string, r PortRangeConfig, configured map[string]string) (string, error) {
+	pa.mux.Lock()
+	defer pa.mux.Unlock()
+
+	for p := r.FROM; p <= r.TO; p++ {
+		port := strconv.Itoa(p)
+		if m, ok := configured[port]; ok && m != module {
+			continue
+		}
+		if pa.reserve(module, port) == nil {
+			return port, nil
+		}
+	}
+	return "", errors.New("no free port in range " + strconv.Itoa(r.FROM) + "-" + strconv.Itoa(r.TO))
+}
+
+//Release - Release ports reserved by module
+func (pa *portAllocator) Release(module string) {
+	pa.mux.Lock()
+	defer pa.mux.Unlock()
+
+	for p, m := range pa.used {
+		if m == module {
+			delete(pa.used, p)
+		}
+	}
+}
+
+//Port - Get port reserved by module, empty if none
+func (pa *portAllocator) Port(module string) string {
+	pa.mux.Lock()
+	defer pa.mux.Unlock()
+
+	for p, m := range pa.used {
+		if m == module {
+			return p
+		}
+	}
+	return ""
+}
+
+func (pa *portAllocator) reserve(module string, port string) error {
+	if pa.used == nil {
+		pa.used = map[string]string{}
+	}
+
+	if m, ok := pa.used[port]; ok && m != module {
+		return errors.New("port " + port + " already used by module " + m)
+	} else if ok {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return errors.New("port " + port + " not available : " + err.Error())
+	}
+	l.Close()
+
+	pa.used[port] = module
+	return nil
+}
+
+//reservePort - Reserve module binding port, allocating one from range if empty. Allocated port is only kept by allocator, never saved in module config
+func (mc *ModuleConfig) reservePort() (string, error) {
+	pa := GetManager().GetPortAllocator()
+	if mc.BINDING.PORT != "" {
+		return mc.BINDING.PORT, pa.Reserve(mc.NAME, mc.BINDING.PORT)
+	}
+
+	//PORTS CONFIGURED FOR MODULES NOT STARTED YET ARE NOT FREE
+	configured := map[string]string{}
+	for _, m := range GetManager().GetModules() {
+		if m.BINDING.PORT != "" && !strings.Contains(m.TYPES, "bind") {
+			configured[m.BINDING.PORT] = m.NAME
+		}
+	}
+	return pa.Allocate(mc.NAME, GetManager().GetConfig().PORTS, configured)
+}
+
+//port - Get module binding port, or port allocated to it while it runs
+func (mc *ModuleConfig) port() string {
+	if mc.BINDING.PORT != "" {
+		return mc.BINDING.PORT
+	}
+	return GetManager().GetPortAllocator().Port(mc.NAME)
+}
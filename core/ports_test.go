@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestPortAllocator(t *testing.T) {
+	//FIRST PORT OF RANGE IS HELD BY ANOTHER PROCESS
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+	r := PortRangeConfig{FROM: busy, TO: busy + 2}
+	p1, p2 := strconv.Itoa(busy+1), strconv.Itoa(busy+2)
+
+	var pa portAllocator
+	if p, err := pa.Allocate("a", r, nil); err != nil || p != p1 {
+		t.Fatalf("allocate a = %s, %v, want %s", p, err, p1)
+	}
+	if err := pa.Reserve("a", p1); err != nil {
+		t.Errorf("reserve own port : %v", err)
+	}
+	if err := pa.Reserve("b", p1); err == nil {
+		t.Errorf("reserve port of a for b : no error")
+	}
+
+	//PORT CONFIGURED FOR c IS SKIPPED FOR b, RANGE IS THEN EXHAUSTED
+	if p, err := pa.Allocate("b", r, map[string]string{p2: "c"}); err == nil {
+		t.Errorf("allocate b with range exhausted = %s, want error", p)
+	}
+	if p, err := pa.Allocate("c", r, map[string]string{p2: "c"}); err != nil || p != p2 {
+		t.Errorf("allocate c = %s, %v, want %s", p, err, p2)
+	}
+
+	//RELEASED PORT GOES BACK TO RANGE
+	pa.Release("a")
+	if p := pa.Port("a"); p != "" {
+		t.Errorf("port of released a = %s", p)
+	}
+	if p, err := pa.Allocate("b", r, nil); err != nil || p != p1 {
+		t.Errorf("allocate b after release = %s, %v, want %s", p, err, p1)
+	}
+}
+
+func TestReservePort(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := l.Addr().(*net.TCPAddr).Port
+	configured := strconv.Itoa(busy + 1)
+	allocated := strconv.Itoa(busy + 2)
+
+	GetManager().SetState(&Config{
+		LOG:   LogConfig{BUFFER: 100},
+		PORTS: PortRangeConfig{FROM: busy, TO: busy + 2},
+		MODULES: map[string]ModuleConfig{
+			"fixed": {NAME: "fixed", TYPES: "web", BINDING: ServerConfig{PORT: configured}},
+			"free":  {NAME: "free", TYPES: "web"},
+		},
+	})
+	defer GetManager().GetPortAllocator().Release("free")
+
+	mc := GetManager().GetModule("free")
+	p, err := mc.reservePort()
+	l.Close()
+	if err != nil || p != allocated {
+		t.Fatalf("reserve port = %s, %v, want %s", p, err, allocated)
+	}
+	if p := mc.port(); p != allocated {
+		t.Errorf("port while running = %s, want %s", p, allocated)
+	}
+	if m := GetManager().GetModule("free"); m.BINDING.PORT != "" {
+		t.Errorf("allocated port saved in config : %s", m.BINDING.PORT)
+	}
+
+	GetManager().GetPortAllocator().Release("free")
+	if p := mc.port(); p != "" {
+		t.Errorf("port after release = %s, want none", p)
+	}
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//StopConfig - Grace periods of module stop sequence
+type StopConfig struct {
+	SHUTDOWN time.Duration
+	TERM     time.Duration
+}
+
+//moduleProcess - Process started by core for a module, leader of its process group
+type moduleProcess struct {
+	done    chan struct{}
+	process *os.Process
+}
+
+const (
+	defaultShutdownGrace = 10 * time.Second
+	defaultTermGrace     = 5 * time.Second
+	killWait             = 5 * time.Second
+)
+
+//grace - Get time given to module after Shutdown command and after SIGTERM
+func (s StopConfig) grace() (time.Duration, time.Duration) {
+	shutdown, term := s.SHUTDOWN, s.TERM
+	if shutdown <= 0 {
+		shutdown = defaultShutdownGrace
+	}
+	if term <= 0 {
+		term = defaultTermGrace
+	}
+	return shutdown, term
+}
+
+//running - Check if process or one of its group is still running
+func (p *moduleProcess) running() bool {
+	select {
+	case <-p.done:
+		return groupRunning(p.process.Pid)
+	default:
+		return true
+	}
+}
+
+//wait - Wait until process group exited, false on timeout or when ctx is done
+func (p *moduleProcess) wait(ctx context.Context, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for p.running() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return true
+}
+
+//Stop - Ask module to shutdown, then SIGTERM and SIGKILL its process group if it is still running after grace periods
+func (mc *ModuleConfig) Stop(ctx context.Context) error {
+	p := GetManager().GetProcess(mc.NAME)
+	if p == nil {
+		//MODULE NOT STARTED BY CORE, ONLY ASK IT
+		if err := mc.shutdown(); err != nil {
+			return err
+		}
+		//NO PROCESS TO SIGNAL, WAIT AS LONG AS SHUTDOWN AND SIGTERM GRACE PERIODS
+		if mc.pid != 0 {
+			shutdown, term := mc.EXE.STOP.grace()
+			deadline := time.Now().Add(shutdown + term)
+			for checkModuleRunning(*mc) {
+				if time.Now().After(deadline) {
+					return errors.New("mod " + mc.NAME + " still running " + (shutdown + term).String() + " after Shutdown command")
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second):
+				}
+			}
+		}
+		GetManager().StopHealthWatch(mc.NAME)
+		mc.STATE = Stopped
+		GetManager().GetSupervisor().Remove(mc.NAME)
+		return nil
+	}
+
+	GetManager().GetSupervisor().Remove(mc.NAME)
+	shutdown, term := mc.EXE.STOP.grace()
+	if err := mc.shutdown(); err != nil {
+		log.Println("GO-WOXY Core - Mod", mc.NAME, "refused Shutdown command :", err)
+	} else if p.wait(ctx, shutdown) {
+		mc.STATE = Stopped
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	log.Println("GO-WOXY Core - Mod", mc.NAME, "still running, sending SIGTERM")
+	if err := signalGroup(p.process, syscall.SIGTERM); err != nil {
+		log.Println("GO-WOXY Core - Error sending SIGTERM to mod", mc.NAME, ":", err)
+	}
+	if p.wait(ctx, term) {
+		mc.STATE = Stopped
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	log.Println("GO-WOXY Core - Mod", mc.NAME, "still running, sending SIGKILL")
+	return mc.Kill()
+}
+
+//Kill - SIGKILL module process group
+func (mc *ModuleConfig) Kill() error {
+	p := GetManager().GetProcess(mc.NAME)
+	if p == nil {
+		return errors.New("mod " + mc.NAME + " has no process started by go-woxy")
+	}
+
+	GetManager().GetSupervisor().Remove(mc.NAME)
+	if err := signalGroup(p.process, syscall.SIGKILL); err != nil {
+		return err
+	}
+	if !p.wait(context.Background(), killWait) {
+		return errors.New("mod " + mc.NAME + " still running after SIGKILL")
+	}
+	mc.STATE = Stopped
+	return nil
+}
+
+//shutdown - Send Shutdown command to module
+func (mc *ModuleConfig) shutdown() error {
+	var cr com.CommandRequest
+	cr.Generate("Shutdown", mc.PK, mc.NAME, GetManager().GetConfig().SECRET)
+	rqtS, err := com.SendRequest(mc.GetServer("/cmd"), &cr, false)
+	if !moduleSucceeded(rqtS, "SHUTTING DOWN "+mc.NAME) && (err == nil || !strings.Contains(err.Error(), "An existing connection was forcibly closed by the remote host")) {
+		if res, ok := com.ParseCommandResult(rqtS); ok && err == nil {
+			err = res.Err()
+		} else if err == nil {
+			err = errors.New(rqtS)
+		}
+		return err
+	}
+	return nil
+}
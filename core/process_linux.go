@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+//signalGroup - Send signal to every process of module process group
+func signalGroup(p *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-p.Pid, sig)
+	if err == syscall.ESRCH {
+		return nil
+	}
+	return err
+}
+
+//groupRunning - Check if a process of group is still running
+func groupRunning(pgid int) bool {
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+//signalGroup - Process groups are only supported on linux, signal module process only
+func signalGroup(p *os.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return p.Kill()
+	}
+	return p.Signal(sig)
+}
+
+//groupRunning - Process groups are only supported on linux
+func groupRunning(pgid int) bool {
+	return false
+}
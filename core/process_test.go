@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStopExternalModule(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("SHUTTING DOWN m"))
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+
+	tests := []struct {
+		name    string
+		cancel  bool
+		wantErr string
+	}{
+		{"still running after grace periods", false, "still running 2s after Shutdown command"},
+		{"canceled", true, context.Canceled.Error()},
+	}
+	for _, tt := range tests {
+		GetManager().SetState(&Config{LOG: LogConfig{BUFFER: 100}})
+		//MODULE ANSWERS SHUTDOWN BUT ITS PID (TEST PROCESS) KEEPS RUNNING
+		mc := ModuleConfig{
+			NAME:    "m",
+			BINDING: ServerConfig{ADDRESS: host, PORT: port, PROTOCOL: "http"},
+			EXE:     ModuleExecConfig{SRC: "./m", STOP: StopConfig{SHUTDOWN: time.Second, TERM: time.Second}},
+			STATE:   Online,
+			pid:     os.Getpid(),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancel {
+			time.AfterFunc(100*time.Millisecond, cancel)
+		}
+		start := time.Now()
+		err := mc.Stop(ctx)
+		cancel()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s : Stop error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("%s : Stop returned after %v", tt.name, d)
+		}
+		if mc.STATE != Online {
+			t.Errorf("%s : state = %s, want %s", tt.name, mc.STATE, Online)
+		}
+	}
+}
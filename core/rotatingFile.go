@@ -0,0 +1,116 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+//rotatingFile - io.Writer writing to a file rotated on size and age
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+
+	mux    sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, maxFiles int) *rotatingFile {
+	return &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxFiles: maxFiles}
+}
+
+//Write - Write p to current file, rotating it before if needed
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mux.Lock()
+	defer rf.mux.Unlock()
+
+	if rf.file == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if (rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize) ||
+		(rf.maxAge > 0 && time.Since(rf.opened) > rf.maxAge) {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+//Close - Close current file
+func (rf *rotatingFile) Close() error {
+	rf.mux.Lock()
+	defer rf.mux.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+func (rf *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(rf.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	rf.file = f
+	rf.size = 0
+	rf.opened = time.Now()
+	if fi, err := f.Stat(); err == nil {
+		rf.size = fi.Size()
+		if rf.size > 0 {
+			rf.opened = fi.ModTime()
+		}
+	}
+	return nil
+}
+
+func (rf *rotatingFile) rotate() error {
+	if rf.file != nil {
+		rf.file.Close()
+		rf.file = nil
+	}
+
+	if err := os.Rename(rf.path, rf.path+"."+time.Now().Format("20060102-150405.000")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	rf.prune()
+
+	return rf.open()
+}
+
+//prune - Remove oldest rotated files over maxFiles
+func (rf *rotatingFile) prune() {
+	if rf.maxFiles <= 0 {
+		return
+	}
+
+	old, err := filepath.Glob(rf.path + ".*")
+	if err != nil || len(old) <= rf.maxFiles {
+		return
+	}
+
+	//TIMESTAMP SUFFIX KEEP LEXICAL ORDER == CHRONOLOGICAL ORDER
+	sort.Strings(old)
+	for _, f := range old[:len(old)-rf.maxFiles] {
+		os.Remove(f)
+	}
+}
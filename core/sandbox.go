@@ -0,0 +1,158 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//IsolationConfig - Linux namespaces created for module process
+type IsolationConfig struct {
+	MOUNT   bool
+	NETWORK bool
+	PID     bool
+}
+
+//inheritedEnv - Core environment variables kept in module clean environment
+var inheritedEnv = []string{"PATH", "LANG", "LC_ALL", "TZ", "GOROOT", "GOPATH", "GOPROXY", "GOPRIVATE", "GONOSUMDB", "GOSUMDB", "GOFLAGS", "GOCACHE", "GOMODCACHE"}
+
+func (i IsolationConfig) enabled() bool {
+	return i.MOUNT || i.NETWORK || i.PID
+}
+
+//workPath - Path next to module directory, out of its checkout
+func (mc *ModuleConfig) workPath(suffix string) string {
+	return filepath.Join(mc.EXE.BIN, "..", "."+mc.NAME+"-"+suffix)
+}
+
+//baseEnvironment - Get environment module starts with : core one if inherited, else a minimal one with private HOME and TMPDIR
+func (mc *ModuleConfig) baseEnvironment() []string {
+	if mc.EXE.INHERIT_ENV {
+		return os.Environ()
+	}
+
+	var env []string
+	for _, k := range inheritedEnv {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+
+	if dir, err := filepath.Abs(mc.workPath("home")); err == nil {
+		env = append(env, "HOME="+dir, "TMPDIR="+filepath.Join(dir, ".tmp"))
+	}
+	return env
+}
+
+//prepareWorkDir - Give module user a private HOME and read access to module directory
+//Module directory stays owned by core, so git commands run by core do not refuse it as dubious ownership
+//Without user (uid < 0) module runs as core and directory permissions are left as they are
+func (mc *ModuleConfig) prepareWorkDir(uid int, gid int) error {
+	home := mc.workPath("home")
+	if err := os.MkdirAll(filepath.Join(home, ".tmp"), 0700); err != nil {
+		return err
+	}
+
+	dir := strings.TrimSuffix(mc.EXE.BIN, "/")
+	if err := restoreOwner(dir); err != nil {
+		return err
+	}
+	if uid < 0 {
+		return nil
+	}
+
+	for _, p := range []string{home, filepath.Join(home, ".tmp")} {
+		if err := os.Lchown(p, uid, gid); err != nil {
+			return err
+		}
+	}
+	//ONLY CORE AND MODULE GROUP ENTER MODULE DIRECTORY, MODULE USER OWNS ITS SECRET FILE
+	if err := os.Chown(dir, os.Getuid(), gid); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, 0750); err != nil {
+		return err
+	}
+	if err := os.Lchown(filepath.Join(dir, ".secret"), uid, gid); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//restoreOwner - Give back to core a module directory owned by module user by previous versions
+func restoreOwner(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if uid, ok := fileOwner(fi); !ok || uid == os.Getuid() {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, os.Getuid(), os.Getgid())
+	})
+}
+
+//compile - Build module binary with core toolchain and environment, outside of module limits and credentials
+func (mc *ModuleConfig) compile(output io.Writer) (string, error) {
+	bin, err := filepath.Abs(mc.workPath("bin"))
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("go", "build", "-o", bin, mc.EXE.MAIN)
+	cmd.Dir = mc.EXE.BIN
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Run(); err != nil {
+		return "", errors.New("build : " + err.Error())
+	}
+	return bin, nil
+}
+
+//startProcess - Start module binary with its limits, retrying without namespaces isolation if not permitted
+func (mc *ModuleConfig) startProcess(limits *processLimits, bin string, args []string, env []string, stdout io.Writer, stderr io.Writer) (*exec.Cmd, error) {
+	name, args := limits.command(bin, args)
+	newCmd := func(isolate bool) (*exec.Cmd, error) {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = mc.EXE.BIN
+		cmd.Env = env
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		attr, err := mc.sysProcAttr(isolate)
+		cmd.SysProcAttr = attr
+		limits.prepare(cmd)
+		return cmd, err
+	}
+
+	cmd, err := newCmd(true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil && mc.EXE.ISOLATION.enabled() {
+		log.Println("GO-WOXY Core - Isolation of mod", mc.NAME, "not permitted, starting without :", err)
+		if cmd, err = newCmd(false); err == nil {
+			err = cmd.Start()
+		}
+	}
+	if err != nil {
+		return cmd, err
+	}
+
+	//MODULE BINARY ONLY RUNS ONCE PROCESS IS IN ITS CGROUP
+	if err := limits.join(cmd.Process.Pid); err != nil {
+		cmd.Wait()
+		return nil, err
+	}
+	return cmd, nil
+}
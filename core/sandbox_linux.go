@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+//sysProcAttr - Get module process credentials and namespaces
+func (mc *ModuleConfig) sysProcAttr(isolate bool) (*syscall.SysProcAttr, error) {
+	//OWN PROCESS GROUP SO STOP SIGNALS REACH PROCESSES STARTED BY MODULE
+	attr := syscall.SysProcAttr{Setpgid: true}
+
+	uid, gid, err := mc.credentials()
+	if err != nil {
+		return nil, err
+	}
+	if err := mc.prepareWorkDir(uid, gid); err != nil {
+		return nil, err
+	}
+	if uid >= 0 {
+		attr.Credential = &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	}
+
+	if isolate {
+		i := mc.EXE.ISOLATION
+		if i.MOUNT {
+			attr.Cloneflags |= syscall.CLONE_NEWNS
+		}
+		if i.PID {
+			attr.Cloneflags |= syscall.CLONE_NEWPID
+		}
+	}
+	return &attr, nil
+}
+
+//credentials - Resolve module USER and GROUP, -1 when module runs as core user
+func (mc *ModuleConfig) credentials() (int, int, error) {
+	if mc.EXE.USER == "" && mc.EXE.GROUP == "" {
+		return -1, -1, nil
+	}
+
+	uid, gid := os.Getuid(), os.Getgid()
+	if mc.EXE.USER != "" {
+		u, err := user.Lookup(mc.EXE.USER)
+		if err != nil {
+			if u, err = user.LookupId(mc.EXE.USER); err != nil {
+				return 0, 0, errors.New("unknown user " + mc.EXE.USER)
+			}
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+
+	if mc.EXE.GROUP != "" {
+		g, err := user.LookupGroup(mc.EXE.GROUP)
+		if err != nil {
+			if g, err = user.LookupGroupId(mc.EXE.GROUP); err != nil {
+				return 0, 0, errors.New("unknown group " + mc.EXE.GROUP)
+			}
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	//NEVER RUN MODULE WITH CORE USER WHEN ANOTHER ONE IS ASKED
+	if os.Geteuid() != 0 && (uid != os.Geteuid() || gid != os.Getegid()) {
+		return 0, 0, errors.New("go-woxy must run as root to start module as " + mc.EXE.USER + ":" + mc.EXE.GROUP)
+	}
+	return uid, gid, nil
+}
+
+//fileOwner - Get uid owning file
+func fileOwner(fi os.FileInfo) (int, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepareWorkDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		uid       int
+		dirMode   os.FileMode
+		homeOwner int
+		legacy    bool
+	}{
+		{"core", -1, 0755, os.Getuid(), false},
+		{"user", 65534, 0750, 65534, false},
+		{"owned by user", 65534, 0750, 65534, true},
+	}
+	for _, tt := range tests {
+		if tt.uid >= 0 && os.Geteuid() != 0 {
+			t.Logf("%s : skipped, needs root", tt.name)
+			continue
+		}
+
+		root, err := ioutil.TempDir("", "woxy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(root)
+		mc := ModuleConfig{NAME: "m", EXE: ModuleExecConfig{BIN: filepath.Join(root, "m") + "/"}}
+		os.MkdirAll(filepath.Join(root, "m", ".git"), 0755)
+		os.Chmod(filepath.Join(root, "m"), 0755)
+		ioutil.WriteFile(filepath.Join(root, "m", ".secret"), []byte("s"), 0600)
+		if tt.legacy {
+			os.Lchown(filepath.Join(root, "m"), tt.uid, tt.uid)
+			os.Lchown(filepath.Join(root, "m", ".git"), tt.uid, tt.uid)
+		}
+
+		if err := mc.prepareWorkDir(tt.uid, tt.uid); err != nil {
+			t.Fatalf("%s : prepareWorkDir : %v", tt.name, err)
+		}
+		fi, _ := os.Stat(filepath.Join(root, "m"))
+		if uid, _ := fileOwner(fi); uid != os.Getuid() || fi.Mode().Perm() != tt.dirMode {
+			t.Errorf("%s : module directory owner %d mode %v, want %d %v", tt.name, uid, fi.Mode().Perm(), os.Getuid(), tt.dirMode)
+		}
+		fi, _ = os.Stat(filepath.Join(root, "m", ".git"))
+		if uid, _ := fileOwner(fi); uid != os.Getuid() {
+			t.Errorf("%s : checkout owner %d, want core %d", tt.name, uid, os.Getuid())
+		}
+		fi, err = os.Stat(filepath.Join(root, ".m-home", ".tmp"))
+		if err != nil {
+			t.Fatalf("%s : private tmp : %v", tt.name, err)
+		}
+		if uid, _ := fileOwner(fi); uid != tt.homeOwner || fi.Mode().Perm() != 0700 {
+			t.Errorf("%s : home owner %d mode %v, want %d 0700", tt.name, uid, fi.Mode().Perm(), tt.homeOwner)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package core
+
+import (
+	"errors"
+	"log"
+	"os"
+	"syscall"
+)
+
+//sysProcAttr - Users and namespaces are only supported on linux
+func (mc *ModuleConfig) sysProcAttr(isolate bool) (*syscall.SysProcAttr, error) {
+	if mc.EXE.USER != "" || mc.EXE.GROUP != "" {
+		return nil, errors.New("module user and group are only supported on linux")
+	}
+	if isolate && mc.EXE.ISOLATION.enabled() {
+		log.Println("GO-WOXY Core - Isolation of mod", mc.NAME, "ignored : only supported on linux")
+	}
+	return nil, mc.prepareWorkDir(-1, -1)
+}
+
+//fileOwner - File owners are only checked on linux
+func fileOwner(fi os.FileInfo) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//labelRequirement - Condition on one module label
+type labelRequirement struct {
+	key    string
+	op     string
+	values []string
+}
+
+//labelSelector - Conditions all matched by selected modules
+type labelSelector []labelRequirement
+
+//Selector operators
+const (
+	selectorEquals    = "="
+	selectorExists    = "exists"
+	selectorIn        = "in"
+	selectorNotEquals = "!="
+	selectorNotExists = "!"
+	selectorNotIn     = "notin"
+)
+
+//parseSelector - Parse label selector
+//(example : env=prod,tier!=batch,region in (eu,us),canary,!legacy)
+func parseSelector(s string) (labelSelector, error) {
+	var res labelSelector
+	for _, part := range splitSelector(s) {
+		part = strings.TrimSpace(part)
+		var r labelRequirement
+		switch {
+		case part == "":
+			continue
+		case strings.Contains(part, " notin "), strings.Contains(part, " in "):
+			op := selectorIn
+			if strings.Contains(part, " notin ") {
+				op = selectorNotIn
+			}
+			kv := strings.SplitN(part, " "+op+" ", 2)
+			set := strings.TrimSpace(kv[1])
+			if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+				return nil, errors.New("invalid selector set : " + part)
+			}
+			r = labelRequirement{key: strings.TrimSpace(kv[0]), op: op}
+			for _, v := range strings.Split(set[1:len(set)-1], ",") {
+				r.values = append(r.values, strings.TrimSpace(v))
+			}
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = labelRequirement{key: strings.TrimSpace(kv[0]), op: selectorNotEquals, values: []string{strings.TrimSpace(kv[1])}}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(strings.Replace(part, "==", "=", 1), "=", 2)
+			r = labelRequirement{key: strings.TrimSpace(kv[0]), op: selectorEquals, values: []string{strings.TrimSpace(kv[1])}}
+		case strings.HasPrefix(part, "!"):
+			r = labelRequirement{key: strings.TrimSpace(part[1:]), op: selectorNotExists}
+		default:
+			r = labelRequirement{key: part, op: selectorExists}
+		}
+
+		if r.key == "" || strings.ContainsAny(r.key, " ()!=") {
+			return nil, errors.New("invalid selector key : " + part)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+//splitSelector - Split selector on commas outside of sets
+func splitSelector(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+//matches - Check labels match all requirements of selector
+func (ls labelSelector) matches(labels map[string]string) bool {
+	for _, r := range ls {
+		v, ok := labels[r.key]
+		switch r.op {
+		case selectorExists:
+			if !ok {
+				return false
+			}
+		case selectorNotExists:
+			if ok {
+				return false
+			}
+		case selectorEquals, selectorIn:
+			if !ok || !contains(r.values, v) {
+				return false
+			}
+		case selectorNotEquals, selectorNotIn:
+			if ok && contains(r.values, v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+//selectModules - Get modules named in target (comma separated, * for all) and matching selector, in start order
+//Errors are CommandError with BAD_REQUEST or NOT_FOUND code
+func (c *Config) selectModules(target string, selector string) ([]ModuleConfig, error) {
+	ls, err := parseSelector(selector)
+	if err != nil {
+		return nil, com.NewCommandError(com.CodeBadRequest, err.Error())
+	}
+
+	mods := GetManager().GetModules()
+	names := c.moduleNames()
+	if target != "" && target != "*" {
+		names = nil
+		for _, n := range strings.Split(target, ",") {
+			n = strings.TrimSpace(n)
+			if _, ok := mods[n]; !ok {
+				return nil, com.NewCommandError(com.CodeNotFound, "module "+n+" not found")
+			}
+			names = append(names, n)
+		}
+	}
+
+	var res []ModuleConfig
+	for _, n := range names {
+		if m := mods[n]; ls.matches(m.LABELS) {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     labelSelector
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"env=prod", labelSelector{{"env", selectorEquals, []string{"prod"}}}, false},
+		{"env == prod", labelSelector{{"env", selectorEquals, []string{"prod"}}}, false},
+		{"tier!=batch", labelSelector{{"tier", selectorNotEquals, []string{"batch"}}}, false},
+		{"region in (eu, us)", labelSelector{{"region", selectorIn, []string{"eu", "us"}}}, false},
+		{"region notin (eu)", labelSelector{{"region", selectorNotIn, []string{"eu"}}}, false},
+		{"canary", labelSelector{{"canary", selectorExists, nil}}, false},
+		{"!legacy", labelSelector{{"legacy", selectorNotExists, nil}}, false},
+		{"env=prod,region in (eu,us), canary,,!legacy", labelSelector{
+			{"env", selectorEquals, []string{"prod"}},
+			{"region", selectorIn, []string{"eu", "us"}},
+			{"canary", selectorExists, nil},
+			{"legacy", selectorNotExists, nil},
+		}, false},
+		{"region in eu", nil, true},
+		{"region in (eu", nil, true},
+		{"=prod", nil, true},
+		{"my env=prod", nil, true},
+		{"!", nil, true},
+		{"a b", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseSelector(tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSelector(%q) error = %v, want error %v", tt.selector, err, tt.wantErr)
+		} else if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSelector(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "region": "eu", "canary": ""}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"tier!=batch", true},
+		{"region in (us,eu)", true},
+		{"region notin (us,eu)", false},
+		{"tier notin (batch)", true},
+		{"tier in (batch)", false},
+		{"canary", true},
+		{"!canary", false},
+		{"!legacy,env=prod,region in (eu)", true},
+		{"env=prod,legacy", false},
+	}
+	for _, tt := range tests {
+		ls, err := parseSelector(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ls.matches(labels); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestSelectModules(t *testing.T) {
+	c := &Config{LOG: LogConfig{BUFFER: 100}, MODULES: map[string]ModuleConfig{
+		"api":    {NAME: "api", LABELS: map[string]string{"tier": "web"}, DEPENDS_ON: []Dependency{{NAME: "db"}}},
+		"db":     {NAME: "db", LABELS: map[string]string{"tier": "data"}},
+		"worker": {NAME: "worker", LABELS: map[string]string{"tier": "batch"}},
+	}}
+	if err := c.checkDependencies(); err != nil {
+		t.Fatal(err)
+	}
+	GetManager().SetState(c)
+
+	tests := []struct {
+		target   string
+		selector string
+		want     string
+		code     string
+	}{
+		{"*", "", "db,api,worker", ""},
+		{"", "tier!=batch", "db,api", ""},
+		{"worker,api", "", "worker,api", ""},
+		{"api,worker", "tier in (web,data)", "api", ""},
+		{"api,missing", "", "", com.CodeNotFound},
+		{"*", "tier in web", "", com.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		mods, err := c.selectModules(tt.target, tt.selector)
+		if tt.code != "" {
+			if ce, ok := err.(*com.CommandError); !ok || ce.Code != tt.code {
+				t.Errorf("selectModules(%q, %q) error = %v, want code %s", tt.target, tt.selector, err, tt.code)
+			}
+			continue
+		}
+		var names []string
+		for _, m := range mods {
+			names = append(names, m.NAME)
+		}
+		if err != nil || strings.Join(names, ",") != tt.want {
+			t.Errorf("selectModules(%q, %q) = %v, %v, want %s", tt.target, tt.selector, names, err, tt.want)
+		}
+	}
+}
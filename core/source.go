@@ -0,0 +1,340 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//Module source types
+const (
+	archiveSource = "archive"
+	gitSource     = "git"
+	localSource   = "local"
+	unknownSource = "unknown"
+)
+
+//sourceTimeout - Longest download of an archive or its signature
+const sourceTimeout = 5 * time.Minute
+
+//sourceClient - HTTP client downloading archives, never waiting forever on a stalled server
+var sourceClient = &http.Client{Timeout: sourceTimeout}
+
+//sourceType - Get kind of module source from EXE.SRC
+//Local directories need an absolute or ./ ../ path, any other src which is not an archive, an URL or a git@ address is unknown
+func (mc *ModuleConfig) sourceType() string {
+	src := mc.EXE.SRC
+	if src == "" {
+		return ""
+	}
+
+	p := sourcePath(src)
+	switch {
+	case strings.HasSuffix(p, ".tar.gz") || strings.HasSuffix(p, ".tgz") || strings.HasSuffix(p, ".zip"):
+		return archiveSource
+	case strings.Contains(src, "://") || strings.HasPrefix(src, "git@"):
+		return gitSource
+	case filepath.IsAbs(src) || strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../"):
+		return localSource
+	}
+	return unknownSource
+}
+
+//downloadArchive - Download, verify and extract module archive into ./mods/<name>
+func (mc *ModuleConfig) downloadArchive(ctx context.Context) error {
+	if mc.EXE.SHA256 == "" {
+		return errors.New("sha256 is mandatory for archive source " + mc.EXE.SRC)
+	}
+
+	b, err := readSource(ctx, mc.EXE.SRC)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(b)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), mc.EXE.SHA256) {
+		return errors.New("sha256 mismatch for " + mc.EXE.SRC + " : got " + hex.EncodeToString(sum[:]))
+	}
+
+	if mc.EXE.PUBLIC_KEY != "" {
+		if err := mc.verifySignature(ctx, b); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := ioutil.TempDir("./mods/", "."+mc.NAME+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if strings.HasSuffix(sourcePath(mc.EXE.SRC), ".zip") {
+		err = extractZip(b, tmp)
+	} else {
+		err = extractTarGz(b, tmp)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := mc.install(rootDir(tmp)); err != nil {
+		return err
+	}
+	fmt.Println("Downloaded  mod : ", mc.NAME, " - archive ", mc.EXE.SRC, " sha256 ", mc.EXE.SHA256)
+	return nil
+}
+
+//copyLocal - Copy module local directory into ./mods/<name>
+func (mc *ModuleConfig) copyLocal() error {
+	fi, err := os.Stat(mc.EXE.SRC)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.New(mc.EXE.SRC + " is not a directory or a supported archive")
+	}
+
+	tmp, err := ioutil.TempDir("./mods/", "."+mc.NAME+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := copyDir(mc.EXE.SRC, filepath.Join(tmp, "src")); err != nil {
+		return err
+	}
+
+	if err := mc.install(filepath.Join(tmp, "src")); err != nil {
+		return err
+	}
+	fmt.Println("Copied  mod : ", mc.NAME, " - from ", mc.EXE.SRC)
+	return nil
+}
+
+//install - Replace ./mods/<name> with dir, old content is restored on failure
+func (mc *ModuleConfig) install(dir string) error {
+	dst := "./mods/" + mc.NAME
+	old := "./mods/." + mc.NAME + ".old"
+
+	os.RemoveAll(old)
+	if err := os.Rename(dst, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.Rename(dir, dst); err != nil {
+		os.Rename(old, dst)
+		return err
+	}
+	os.RemoveAll(old)
+
+	mc.EXE.BIN = dst + "/"
+	return nil
+}
+
+//verifySignature - Check ed25519 signature of archive with module public key
+func (mc *ModuleConfig) verifySignature(ctx context.Context, b []byte) error {
+	pub, err := base64.StdEncoding.DecodeString(mc.EXE.PUBLIC_KEY)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid ed25519 public key for " + mc.NAME)
+	}
+
+	sigSrc := mc.EXE.SIGNATURE
+	if sigSrc == "" {
+		sigSrc = mc.EXE.SRC + ".sig"
+	}
+	sig, err := readSource(ctx, sigSrc)
+	if err != nil {
+		return err
+	}
+
+	//SIGNATURE FILE CAN BE RAW OR BASE64 ENCODED
+	if len(sig) != ed25519.SignatureSize {
+		if sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+			return errors.New("invalid signature file " + sigSrc)
+		}
+	}
+
+	if !ed25519.Verify(ed25519.PublicKey(pub), b, sig) {
+		return errors.New("signature verification failed for " + mc.EXE.SRC)
+	}
+	return nil
+}
+
+//sourcePath - Get lower case path of a module source, without URL scheme and query
+func sourcePath(src string) string {
+	if u, err := url.Parse(src); err == nil && u.Scheme != "" {
+		src = u.Path
+	}
+	return strings.ToLower(src)
+}
+
+//readSource - Read content of an http(s) URL or a local file
+func readSource(ctx context.Context, src string) ([]byte, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := sourceClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("GET " + src + " : " + resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+	return ioutil.ReadFile(strings.TrimPrefix(src, "file://"))
+}
+
+//rootDir - Get single top level directory of extracted archive if any
+func rootDir(dir string) string {
+	src := filepath.Join(dir, "src")
+	l, err := ioutil.ReadDir(src)
+	if err == nil && len(l) == 1 && l[0].IsDir() {
+		return filepath.Join(src, l[0].Name())
+	}
+	return src
+}
+
+//safePath - Join archive entry name to dst, refusing entries escaping dst
+func safePath(dst string, name string) (string, error) {
+	p := filepath.Join(dst, name)
+	if p != dst && !strings.HasPrefix(p, filepath.Clean(dst)+string(os.PathSeparator)) {
+		return "", errors.New("illegal path in archive : " + name)
+	}
+	return p, nil
+}
+
+func extractTarGz(b []byte, dir string) error {
+	dst := filepath.Join(dir, "src")
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		p, err := safePath(dst, h.Name)
+		if err != nil {
+			return err
+		}
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, 0755)
+		case tar.TypeReg:
+			err = writeFile(p, tr, os.FileMode(h.Mode)&0777)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(b []byte, dir string) error {
+	dst := filepath.Join(dir, "src")
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		p, err := safePath(dst, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(p, rc, f.Mode()&0777)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		p := filepath.Join(dst, rel)
+
+		switch {
+		case fi.IsDir():
+			return os.MkdirAll(p, 0755)
+		case fi.Mode()&os.ModeSymlink != 0:
+			l, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(l, p)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(p, f, fi.Mode()&0777)
+	})
+}
+
+func writeFile(p string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
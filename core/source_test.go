@@ -0,0 +1,208 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipFile(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDownloadArchive(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, otherPriv, _ := ed25519.GenerateKey(nil)
+
+	good := tarGz(t, map[string]string{"mod/main.go": "package main"})
+	goodZip := zipFile(t, map[string]string{"main.go": "package main"})
+	archives := map[string][]byte{
+		"/good.tar.gz":     good,
+		"/good.tar.gz.sig": ed25519.Sign(priv, good),
+		"/bad.tar.gz":      good,
+		"/bad.tar.gz.sig":  []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(otherPriv, good))),
+		"/good.zip":        goodZip,
+		"/good.zip.sig":    []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, goodZip))),
+		"/escape.tar.gz":   tarGz(t, map[string]string{"../../escaped.go": "package main"}),
+		"/escape.zip":      zipFile(t, map[string]string{"../../escaped.go": "package main"}),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	sum := func(path string) string {
+		s := sha256.Sum256(archives[path])
+		return hex.EncodeToString(s[:])
+	}
+	key := base64.StdEncoding.EncodeToString(pub)
+
+	tests := []struct {
+		name    string
+		path    string
+		sha256  string
+		key     string
+		cancel  bool
+		wantErr bool
+	}{
+		{"tar.gz", "/good.tar.gz", sum("/good.tar.gz"), "", false, false},
+		{"signed tar.gz", "/good.tar.gz", sum("/good.tar.gz"), key, false, false},
+		{"signed zip", "/good.zip", sum("/good.zip"), key, false, false},
+		{"missing sha256", "/good.tar.gz", "", "", false, true},
+		{"sha256 mismatch", "/good.tar.gz", sum("/good.zip"), "", false, true},
+		{"bad signature", "/bad.tar.gz", sum("/bad.tar.gz"), key, false, true},
+		{"tar path traversal", "/escape.tar.gz", sum("/escape.tar.gz"), "", false, true},
+		{"zip path traversal", "/escape.zip", sum("/escape.zip"), "", false, true},
+		{"not found", "/missing.tar.gz", sum("/good.tar.gz"), "", false, true},
+		{"cancelled", "/good.tar.gz", sum("/good.tar.gz"), "", true, true},
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "woxy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		os.Chdir(dir)
+		os.MkdirAll("mods/m", 0755)
+		ioutil.WriteFile("mods/m/previous.go", []byte("package previous"), 0644)
+
+		mc := ModuleConfig{NAME: "m", EXE: ModuleExecConfig{SRC: srv.URL + tt.path, SHA256: tt.sha256, PUBLIC_KEY: tt.key}}
+		ctx, cancel := context.WithCancel(context.Background())
+		if tt.cancel {
+			cancel()
+		}
+		err = mc.downloadArchive(ctx)
+		cancel()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s : downloadArchive error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+
+		_, prevErr := os.Stat("mods/m/previous.go")
+		_, mainErr := os.Stat("mods/m/main.go")
+		if tt.wantErr && (prevErr != nil || mainErr == nil) {
+			t.Errorf("%s : ./mods/m changed by failed download", tt.name)
+		} else if !tt.wantErr && (prevErr == nil || mainErr != nil) {
+			t.Errorf("%s : ./mods/m not replaced by archive content", tt.name)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escaped.go")); err == nil {
+			t.Errorf("%s : archive entry written outside of module directory", tt.name)
+		}
+		if l, _ := filepath.Glob("mods/.m-*"); len(l) > 0 {
+			t.Errorf("%s : temporary directories left : %v", tt.name, l)
+		}
+	}
+}
+
+func TestSafePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"main.go", "/tmp/dst/main.go", false},
+		{"a/b/../c.go", "/tmp/dst/a/c.go", false},
+		{"./", "/tmp/dst", false},
+		{"../evil.go", "", true},
+		{"a/../../evil.go", "", true},
+		{"../dst2/evil.go", "", true},
+		{"/etc/passwd", "/tmp/dst/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		got, err := safePath("/tmp/dst", tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("safePath(%q) = %q, %v, want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSourceType(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", ""},
+		{"https://github.com/Wariie/mod.git", gitSource},
+		{"git@github.com:Wariie/mod.git", gitSource},
+		{"file:///srv/git/mod", gitSource},
+		{"https://example.org/mod.tar.gz?token=1", archiveSource},
+		{"./dist/mod.TGZ", archiveSource},
+		{"/srv/mod.zip", archiveSource},
+		{"../mod", localSource},
+		{"./mod", localSource},
+		{"/srv/mod", localSource},
+		{"mod", unknownSource},
+		{"github.com/Wariie/mod", unknownSource},
+		{"~/mod", unknownSource},
+	}
+	for _, tt := range tests {
+		mc := ModuleConfig{EXE: ModuleExecConfig{SRC: tt.src}}
+		if got := mc.sourceType(); got != tt.want {
+			t.Errorf("sourceType(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCheckModulesSource(t *testing.T) {
+	tests := []struct {
+		src     string
+		remote  bool
+		wantErr bool
+	}{
+		{"https://github.com/Wariie/mod.git", false, false},
+		{"./mod", false, false},
+		{"mod", false, true},
+		{"github.com/Wariie/mod", false, true},
+		{"mod", true, false},
+	}
+	for _, tt := range tests {
+		c := &Config{MODULES: map[string]ModuleConfig{"m": {EXE: ModuleExecConfig{SRC: tt.src, REMOTE: tt.remote}}}}
+		if err := c.checkModules(); (err != nil) != tt.wantErr {
+			t.Errorf("checkModules with src %q (remote %v) error = %v, want error %v", tt.src, tt.remote, err, tt.wantErr)
+		}
+	}
+}
@@ -8,9 +8,32 @@ import (
 
 type manager struct {
 	config *Config
+	modMux sync.RWMutex
 	router *gin.Engine
 	cp     *CommandProcessorImpl
 	s      *Supervisor
+	logs   map[string]*LogStore
+	logMux sync.Mutex
+
+	deployments []Deployment
+	deployMux   sync.Mutex
+
+	jobs   []Job
+	jobMux sync.Mutex
+
+	ports portAllocator
+
+	procs   map[string]*moduleProcess
+	procMux sync.Mutex
+
+	health    map[string]*HealthStatus
+	healthMux sync.Mutex
+	watches   map[string]*healthWatch
+
+	perf     *perfStore
+	perfOnce sync.Once
+
+	metrics requestMetrics
 }
 
 var singleton *manager
@@ -24,11 +47,17 @@ func GetManager() *manager {
 	return singleton
 }
 
+//GetConfig - Get current config, its MODULES map is only read and written through module accessors
 func (sm *manager) GetConfig() *Config {
+	sm.modMux.RLock()
+	defer sm.modMux.RUnlock()
 	return sm.config
 }
 
+//SetState - Replace current config
 func (sm *manager) SetState(c *Config) {
+	sm.modMux.Lock()
+	defer sm.modMux.Unlock()
 	sm.config = c
 }
 
@@ -56,6 +85,246 @@ func (sm *manager) GetSupervisor() *Supervisor {
 	return sm.s
 }
 
+//GetLogStore - Get LogStore of module, created on first call
+func (sm *manager) GetLogStore(name string) *LogStore {
+	sm.logMux.Lock()
+	defer sm.logMux.Unlock()
+
+	if sm.logs == nil {
+		sm.logs = map[string]*LogStore{}
+	}
+	ls, ok := sm.logs[name]
+	if !ok {
+		ls = newLogStore(name, sm.GetConfig().LOG)
+		sm.logs[name] = ls
+	}
+	return ls
+}
+
+func (sm *manager) GetPortAllocator() *portAllocator {
+	return &sm.ports
+}
+
+//SaveDeployment - Add or update Deployment, oldest ones are dropped
+func (sm *manager) SaveDeployment(d Deployment) {
+	sm.deployMux.Lock()
+	defer sm.deployMux.Unlock()
+
+	d.MESSAGES = append([]string(nil), d.MESSAGES...)
+	for i := range sm.deployments {
+		if sm.deployments[i].ID == d.ID {
+			sm.deployments[i] = d
+			return
+		}
+	}
+
+	sm.deployments = append(sm.deployments, d)
+	if len(sm.deployments) > maxDeployments {
+		sm.deployments = sm.deployments[len(sm.deployments)-maxDeployments:]
+	}
+}
+
+//GetDeployments - Get deployments, oldest first
+func (sm *manager) GetDeployments() []Deployment {
+	sm.deployMux.Lock()
+	defer sm.deployMux.Unlock()
+	return append([]Deployment(nil), sm.deployments...)
+}
+
+//SaveJob - Add or update Job, oldest finished ones are dropped
+func (sm *manager) SaveJob(j Job) {
+	sm.jobMux.Lock()
+	defer sm.jobMux.Unlock()
+
+	j.MODULES = append([]string(nil), j.MODULES...)
+	j.STEPS = append([]JobStep(nil), j.STEPS...)
+	for i := range sm.jobs {
+		if sm.jobs[i].ID == j.ID {
+			sm.jobs[i] = j
+			return
+		}
+	}
+
+	sm.jobs = append(sm.jobs, j)
+	for i := 0; len(sm.jobs) > maxJobs && i < len(sm.jobs); {
+		if sm.jobs[i].finished() {
+			sm.jobs = append(sm.jobs[:i], sm.jobs[i+1:]...)
+		} else {
+			i++
+		}
+	}
+}
+
+//GetJobs - Get jobs, oldest first
+func (sm *manager) GetJobs() []Job {
+	sm.jobMux.Lock()
+	defer sm.jobMux.Unlock()
+	return append([]Job(nil), sm.jobs...)
+}
+
+//GetJob - Get Job by id
+func (sm *manager) GetJob(id string) (Job, bool) {
+	sm.jobMux.Lock()
+	defer sm.jobMux.Unlock()
+	for _, j := range sm.jobs {
+		if j.ID == id {
+			return j, true
+		}
+	}
+	return Job{}, false
+}
+
+//GetProcess - Get process started for module, nil if none is running
+func (sm *manager) GetProcess(name string) *moduleProcess {
+	sm.procMux.Lock()
+	defer sm.procMux.Unlock()
+	return sm.procs[name]
+}
+
+//SetProcess - Set process started for module
+func (sm *manager) SetProcess(name string, p *moduleProcess) {
+	sm.procMux.Lock()
+	defer sm.procMux.Unlock()
+
+	if sm.procs == nil {
+		sm.procs = map[string]*moduleProcess{}
+	}
+	sm.procs[name] = p
+}
+
+//RemoveProcess - Remove process of module if it was not replaced by a newer one
+func (sm *manager) RemoveProcess(name string, p *moduleProcess) {
+	sm.procMux.Lock()
+	defer sm.procMux.Unlock()
+
+	if sm.procs[name] == p {
+		delete(sm.procs, name)
+	}
+}
+
+//GetPerfStore - Get modules performance time-series
+func (sm *manager) GetPerfStore() *perfStore {
+	sm.perfOnce.Do(func() {
+		sm.perf = newPerfStore()
+	})
+	return sm.perf
+}
+
+func (sm *manager) GetMetrics() *requestMetrics {
+	return &sm.metrics
+}
+
+//GetHealth - Get module probes status
+func (sm *manager) GetHealth(name string) HealthStatus {
+	sm.healthMux.Lock()
+	defer sm.healthMux.Unlock()
+
+	if h, ok := sm.health[name]; ok {
+		return *h
+	}
+	return HealthStatus{}
+}
+
+//healthWatch - Probes of a module not started by core, done is closed to stop them
+type healthWatch struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+//stop - Stop probes and wait for them to exit
+func (hw *healthWatch) stop() {
+	close(hw.done)
+	hw.wg.Wait()
+}
+
+//WatchHealth - Get watch of probes of module not started by core, previous probes are stopped
+func (sm *manager) WatchHealth(name string) *healthWatch {
+	hw := &healthWatch{done: make(chan struct{})}
+	sm.healthMux.Lock()
+	if sm.watches == nil {
+		sm.watches = map[string]*healthWatch{}
+	}
+	old := sm.watches[name]
+	sm.watches[name] = hw
+	sm.healthMux.Unlock()
+
+	//PROBES UPDATE HEALTH, THEY ARE WAITED WITHOUT LOCK
+	if old != nil {
+		old.stop()
+	}
+	return hw
+}
+
+//StopHealthWatch - Stop probes of module not started by core and wait for them to exit
+func (sm *manager) StopHealthWatch(name string) {
+	sm.healthMux.Lock()
+	hw, ok := sm.watches[name]
+	delete(sm.watches, name)
+	sm.healthMux.Unlock()
+
+	if ok {
+		hw.stop()
+	}
+}
+
+//SetHealth - Update module probes status
+func (sm *manager) SetHealth(name string, update func(*HealthStatus)) {
+	sm.healthMux.Lock()
+	defer sm.healthMux.Unlock()
+
+	if sm.health == nil {
+		sm.health = map[string]*HealthStatus{}
+	}
+	h, ok := sm.health[name]
+	if !ok {
+		h = &HealthStatus{}
+		sm.health[name] = h
+	}
+	update(h)
+}
+
+//GetModule - Get copy of module config, zero value when unknown
+func (sm *manager) GetModule(name string) ModuleConfig {
+	m, _ := sm.LookupModule(name)
+	return m
+}
+
+//LookupModule - Get copy of module config and whether module is known
+func (sm *manager) LookupModule(name string) (ModuleConfig, bool) {
+	sm.modMux.RLock()
+	defer sm.modMux.RUnlock()
+
+	m, ok := sm.config.MODULES[name]
+	return m, ok
+}
+
+//GetModules - Get copy of modules config
+func (sm *manager) GetModules() map[string]ModuleConfig {
+	sm.modMux.RLock()
+	defer sm.modMux.RUnlock()
+
+	res := make(map[string]ModuleConfig, len(sm.config.MODULES))
+	for k, m := range sm.config.MODULES {
+		res[k] = m
+	}
+	return res
+}
+
+//SaveModuleChanges - Save module config
 func (sm *manager) SaveModuleChanges(mc *ModuleConfig) {
+	sm.modMux.Lock()
+	defer sm.modMux.Unlock()
+
 	sm.config.MODULES[mc.NAME] = *mc
 }
+
+//UpdateModule - Change saved config of known module, without overwriting fields changed by others
+func (sm *manager) UpdateModule(name string, update func(*ModuleConfig)) {
+	sm.modMux.Lock()
+	defer sm.modMux.Unlock()
+
+	if m, ok := sm.config.MODULES[name]; ok {
+		update(&m)
+		sm.config.MODULES[name] = m
+	}
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestModuleAccessors(t *testing.T) {
+	GetManager().SetState(&Config{MODULES: map[string]ModuleConfig{"a": {NAME: "a", PK: "pk", STATE: Loading}}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			GetManager().UpdateModule("a", func(m *ModuleConfig) { m.STATE = Online })
+		}()
+		go func() {
+			defer wg.Done()
+			for n, m := range GetManager().GetModules() {
+				if n != m.NAME {
+					t.Errorf("module %s saved as %s", n, m.NAME)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	tests := []struct {
+		name  string
+		found bool
+		pk    string
+		state ModuleState
+	}{
+		{"a", true, "pk", Online},
+		{"b", false, "", ""},
+	}
+	for _, tt := range tests {
+		GetManager().UpdateModule(tt.name, func(m *ModuleConfig) { m.PK = tt.pk })
+		m, ok := GetManager().LookupModule(tt.name)
+		if ok != tt.found || m.PK != tt.pk || m.STATE != tt.state {
+			t.Errorf("LookupModule(%s) = %s %s, %v, want %s %s, %v", tt.name, m.PK, m.STATE, ok, tt.pk, tt.state, tt.found)
+		}
+	}
+}
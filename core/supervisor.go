@@ -2,7 +2,7 @@ package core
 
 import (
 	"errors"
-	"strings"
+	"reflect"
 	"sync"
 	"time"
 
@@ -19,6 +19,8 @@ type Supervisor struct {
 
 //Remove -
 func (s *Supervisor) Remove(m string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 	for i := range s.listModule {
 		if m == s.listModule[i] {
 			s.listModule[i] = s.listModule[len(s.listModule)-1] // Copy last element to index i.
@@ -42,22 +44,31 @@ func (s *Supervisor) Supervise() {
 	for {
 		//FOR EACH REGISTERED MODULE
 		s.mux.Lock()
+		names := append([]string(nil), s.listModule...)
+		s.mux.Unlock()
 
-		for k := range s.listModule {
-			//CHECK MODULE RUNNING
-			m := GetManager().GetConfig().MODULES[s.listModule[k]]
-			if checkModuleRunning(m) {
-				if m.STATE != Online && m.STATE != Loading && m.STATE != Downloaded {
-					m.STATE = Online
-				}
+		for _, name := range names {
+			//CHECK MODULE RUNNING, ONLY STATE IS UPDATED SO CHANGES MADE MEANWHILE ARE KEPT
+			if checkModuleRunning(GetManager().GetModule(name)) {
+				GetManager().UpdateModule(name, func(m *ModuleConfig) {
+					if !m.STATE.running() && m.STATE != Loading && m.STATE != Downloaded {
+						m.STATE = Online
+					}
+				})
 				//ELSE SET STATE TO UNKNOWN
-			} else if m.STATE != Loading && m.STATE != Downloaded {
-				m.STATE = Unknown
-				s.Remove(m.NAME)
+			} else {
+				removed := false
+				GetManager().UpdateModule(name, func(m *ModuleConfig) {
+					if m.STATE != Loading && m.STATE != Downloaded {
+						m.STATE = Unknown
+						removed = true
+					}
+				})
+				if removed {
+					s.Remove(name)
+				}
 			}
-			GetManager().SaveModuleChanges(&m)
 		}
-		s.mux.Unlock()
 		time.Sleep(time.Millisecond * 10)
 	}
 }
@@ -67,7 +78,7 @@ func checkModuleRunning(mc ModuleConfig) bool {
 	b := false
 
 	for b == false && try < 5 {
-		if mc.pid != 0 && (mc.EXE != ModuleExecConfig{}) {
+		if mc.pid != 0 && !reflect.DeepEqual(mc.EXE, ModuleExecConfig{}) {
 			b = checkPidRunning(&mc)
 		}
 
@@ -85,7 +96,7 @@ func checkModulePing(mc *ModuleConfig) bool {
 	resp, err := com.SendRequest(mc.GetServer("/cmd"), &cr, false)
 	if err != nil {
 		return false
-	} else if strings.Contains(resp, mc.NAME+" ALIVE") {
+	} else if moduleSucceeded(resp, mc.NAME+" ALIVE") {
 		return true
 	}
 	return false
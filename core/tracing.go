@@ -0,0 +1,42 @@
+package core
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+//TracingConfig - Spans export of core and modules
+type TracingConfig struct {
+	ENDPOINT string
+	EXPORTER string
+	PATH     string
+}
+
+//endpoint - Get collector URL for otlp exporter, absolute file path for file exporter
+func (t TracingConfig) endpoint() string {
+	if t.EXPORTER != "file" {
+		return t.ENDPOINT
+	}
+	p, err := filepath.Abs(t.PATH)
+	if err != nil {
+		return t.PATH
+	}
+	return p
+}
+
+//initTracing - Start spans export of core
+func (c *Config) initTracing() {
+	if err := com.InitTracing(c.NAME, c.TRACING.EXPORTER, c.TRACING.endpoint()); err != nil {
+		log.Fatalln("GO-WOXY Core - Error starting tracing :", err)
+	}
+}
+
+//traceEnvironment - Environment telling module where to export its spans
+func traceEnvironment(t TracingConfig) []string {
+	if t.EXPORTER == "" {
+		return nil
+	}
+	return []string{"WOXY_TRACE_EXPORTER=" + t.EXPORTER, "WOXY_TRACE_ENDPOINT=" + t.endpoint()}
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wariie/go-woxy/tools"
+	"github.com/gin-gonic/gin"
+)
+
+//maxDeployments - Number of deployments kept in memory
+const maxDeployments = 50
+
+//WebhookConfig - Signed webhook triggering redeploys
+type WebhookConfig struct {
+	PATH       string
+	SECRET     string
+	SECRET_ENV string
+	TIMEOUT    time.Duration
+}
+
+//DeploymentStatus - Status of a Deployment
+type DeploymentStatus string
+
+const (
+	DeployPending DeploymentStatus = "PENDING"
+	DeployRunning DeploymentStatus = "RUNNING"
+	DeploySuccess DeploymentStatus = "SUCCESS"
+	DeployFailed  DeploymentStatus = "FAILED"
+)
+
+//Deployment - Redeploy of modules triggered by a push
+type Deployment struct {
+	BRANCH     string
+	COMMIT     string
+	FINISHED   time.Time
+	ID         string
+	MESSAGES   []string
+	MODULES    []string
+	REPOSITORY string
+	STARTED    time.Time
+	STATUS     DeploymentStatus
+}
+
+//pushPayload - Fields of GitHub/Gitea push event used to find modules
+type pushPayload struct {
+	After      string `json:"after"`
+	Ref        string `json:"ref"`
+	Repository struct {
+		CloneURL      string `json:"clone_url"`
+		DefaultBranch string `json:"default_branch"`
+		FullName      string `json:"full_name"`
+		HTMLURL       string `json:"html_url"`
+		SSHURL        string `json:"ssh_url"`
+	} `json:"repository"`
+}
+
+//deployMux - Only one deployment runs at a time
+var deployMux sync.Mutex
+
+func (d Deployment) hasModule(name string) bool {
+	for _, m := range d.MODULES {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+//secret - Get webhook secret from config or environment variable
+func (w WebhookConfig) secret() string {
+	if w.SECRET != "" {
+		return w.SECRET
+	}
+	if w.SECRET_ENV != "" {
+		return os.Getenv(w.SECRET_ENV)
+	}
+	return ""
+}
+
+//checkSignature - Check GitHub (X-Hub-Signature-256) or Gitea (X-Gitea-Signature) HMAC of body, always false without secret
+func (w WebhookConfig) checkSignature(h http.Header, body []byte) bool {
+	secret := w.secret()
+	if secret == "" {
+		return false
+	}
+
+	sig := strings.TrimPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+	if sig == "" {
+		sig = h.Get("X-Gitea-Signature")
+	}
+	if sig == "" {
+		sig = h.Get("X-Gogs-Signature")
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil || sig == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+//webhook - Access point for push events, redeploy matching modules
+func webhook(c *gin.Context) {
+	cfg := GetManager().GetConfig().WEBHOOK
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(c.Request.Body)
+
+	if !cfg.checkSignature(c.Request.Header, buf.Bytes()) {
+		log.Println("GO-WOXY Core - Webhook from", c.Request.RemoteAddr, ": invalid signature", "request_id="+c.GetString(requestIDKey))
+		c.String(http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	event := c.GetHeader("X-GitHub-Event")
+	if event == "" {
+		event = c.GetHeader("X-Gitea-Event")
+	}
+	if event == "ping" {
+		c.String(http.StatusOK, "pong")
+		return
+	} else if event != "" && event != "push" {
+		c.String(http.StatusAccepted, "Event %s ignored", event)
+		return
+	}
+
+	var p pushPayload
+	if err := json.Unmarshal(buf.Bytes(), &p); err != nil || !strings.HasPrefix(p.Ref, "refs/heads/") {
+		c.String(http.StatusBadRequest, "Error reading push payload")
+		return
+	}
+	branch := strings.TrimPrefix(p.Ref, "refs/heads/")
+
+	mods, pinned := p.matchModules(branch)
+	var skipped []string
+	for _, name := range pinned {
+		m := GetManager().GetModule(name)
+		skipped = append(skipped, name+" : pinned at "+m.PINNED_COMMIT+" by rollback, skipped")
+		log.Println("GO-WOXY Core - Webhook skipped mod", name, "pinned at", m.PINNED_COMMIT, "request_id="+c.GetString(requestIDKey))
+	}
+	if len(mods) == 0 {
+		c.String(http.StatusAccepted, "No module to deploy for %s %s %s", p.Repository.FullName, branch, strings.Join(skipped, ", "))
+		return
+	}
+
+	d := Deployment{
+		BRANCH:     branch,
+		COMMIT:     p.After,
+		ID:         tools.String(12),
+		MESSAGES:   skipped,
+		MODULES:    mods,
+		REPOSITORY: p.Repository.FullName,
+		STARTED:    time.Now(),
+		STATUS:     DeployPending,
+	}
+	GetManager().SaveDeployment(d)
+	log.Println("GO-WOXY Core - Webhook deployment", d.ID, "of", mods, "for", p.Repository.FullName, branch, "request_id="+c.GetString(requestIDKey))
+
+	go d.run(cfg.TIMEOUT)
+
+	c.JSON(http.StatusAccepted, d)
+}
+
+//matchModules - Get names of git modules following repository and branch of push, in start order
+//Modules pinned by a rollback are returned apart, a push does not release their pin
+func (p pushPayload) matchModules(branch string) ([]string, []string) {
+	urls := []string{normalizeRepoURL(p.Repository.CloneURL), normalizeRepoURL(p.Repository.SSHURL), normalizeRepoURL(p.Repository.HTMLURL)}
+
+	var res, pinned []string
+	mods := GetManager().GetModules()
+	for _, name := range GetManager().GetConfig().moduleNames() {
+		mc := mods[name]
+		if mc.EXE.REMOTE || mc.sourceType() != gitSource {
+			continue
+		}
+
+		ref := mc.EXE.REF
+		if ref == "" {
+			ref = p.Repository.DefaultBranch
+		}
+		if ref != branch {
+			continue
+		}
+
+		src := normalizeRepoURL(mc.EXE.SRC)
+		for _, u := range urls {
+			if u != "" && u == src && mc.PINNED_COMMIT != "" {
+				pinned = append(pinned, name)
+				break
+			} else if u != "" && u == src {
+				res = append(res, name)
+				break
+			}
+		}
+	}
+	return res, pinned
+}
+
+//normalizeRepoURL - Reduce git URL to host/path to compare https and ssh forms
+func normalizeRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if u == "" {
+		return ""
+	}
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	} else if strings.HasPrefix(u, "git@") {
+		u = strings.Replace(u, ":", "/", 1)
+	}
+	if i := strings.Index(u, "@"); i >= 0 {
+		u = u[i+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+}
+
+//run - Deploy modules one after another, stopping at first failure
+func (d Deployment) run(timeout time.Duration) {
+	deployMux.Lock()
+	defer deployMux.Unlock()
+
+	d.STATUS = DeployRunning
+	GetManager().SaveDeployment(d)
+
+	for _, name := range d.MODULES {
+		mc := GetManager().GetModule(name)
+		msg, err := mc.deploy(context.Background(), timeout)
+		if err != nil {
+			d.MESSAGES = append(d.MESSAGES, name+" : "+err.Error())
+			d.STATUS = DeployFailed
+			break
+		}
+		d.MESSAGES = append(d.MESSAGES, name+" : "+msg)
+		GetManager().SaveDeployment(d)
+	}
+
+	if d.STATUS != DeployFailed {
+		d.STATUS = DeploySuccess
+	}
+	d.FINISHED = time.Now()
+	GetManager().SaveDeployment(d)
+	log.Println("GO-WOXY Core - Deployment", d.ID, d.STATUS, d.MESSAGES)
+}
+
+//deploy - Fetch module ref, check it builds, then restart module on it and wait for it to come online if it was running
+//Commit pinned by a rollback is released so module follows its ref again, webhook pushes skip pinned modules
+func (mc *ModuleConfig) deploy(ctx context.Context, timeout time.Duration) (string, error) {
+	if _, err := os.Stat(mc.EXE.BIN); err != nil {
+		return "", errors.New("module not downloaded")
+	}
+
+	commit, err := mc.resolveRef(ctx)
+	if err != nil {
+		return "", err
+	}
+	if mc.PINNED_COMMIT != "" {
+		mc.PINNED_COMMIT = ""
+		GetManager().SaveModuleChanges(mc)
+	}
+	if commit == mc.COMMIT {
+		return "already at " + commit, nil
+	}
+
+	if err := mc.build(ctx, commit); err != nil {
+		return "", err
+	}
+
+	//MODULE STOPPED BY AN ADMIN OR NOT STARTED IS ONLY MOVED TO NEW COMMIT
+	running := mc.STATE.running()
+	if running {
+		if err := mc.Stop(ctx); err != nil {
+			return "", errors.New("stopping : " + err.Error())
+		}
+	}
+
+	if err := mc.checkout(ctx, commit); err != nil {
+		return "", err
+	}
+	if !running {
+		GetManager().SaveModuleChanges(mc)
+		return "checked out " + commit + ", not started as module was " + string(mc.STATE), nil
+	}
+	mc.STATE = Loading
+	GetManager().SaveModuleChanges(mc)
+
+	mc.copySecret()
+	m := *mc
+	go m.Start()
+
+	if err := waitModuleOnline(ctx, mc.NAME, timeout); err != nil {
+		return "", errors.New(err.Error() + " on " + commit)
+	}
+	return "deployed " + commit, nil
+}
+
+//build - Build commit in a temporary worktree so a broken commit never stops running module
+func (mc *ModuleConfig) build(ctx context.Context, commit string) error {
+	dir := "../." + mc.NAME + "-build"
+	mc.git(ctx, mc.EXE.BIN, "worktree", "remove", "--force", dir)
+	if _, err := mc.git(ctx, mc.EXE.BIN, "worktree", "add", "--detach", "--force", dir, commit); err != nil {
+		return err
+	}
+	defer mc.git(ctx, mc.EXE.BIN, "worktree", "remove", "--force", dir)
+
+	cmd := exec.CommandContext(ctx, "go", "build", "-o", os.DevNull, mc.EXE.MAIN)
+	cmd.Dir = mc.EXE.BIN + dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.New("build : " + err.Error() + " - " + strings.TrimSpace(string(out)))
+	}
+	return nil
+}
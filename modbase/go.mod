@@ -3,11 +3,8 @@ module github.com/Wariie/go-woxy/modbase
 go 1.15
 
 require (
-	github.com/Wariie/go-woxy/com v0.0.0-20200831140926-4e2b8c3ff239
-	github.com/Wariie/go-woxy/tools v0.0.0-20200831140926-4e2b8c3ff239 // indirect
-	github.com/antonfisher/nested-logrus-formatter v1.2.0
+	github.com/Wariie/go-woxy/com v0.1.0
 	github.com/foolin/goview v0.3.0
-	github.com/gin-contrib/logger v0.0.2
 	github.com/gin-contrib/static v0.0.0-20200815103939-31fb0c56a3d1
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-playground/validator/v10 v10.3.0 // indirect
@@ -17,10 +14,7 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/rs/zerolog v1.19.0
-	github.com/shiena/ansicolor v0.0.0-20200830101100-9405ca8e49f3
-	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1 // indirect
-	github.com/toorop/gin-logrus v0.0.0-20200831135515-d2ee50d38dae
 	golang.org/x/sys v0.0.0-20200831180312-196b9ba8737a // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
 	google.golang.org/protobuf v1.25.0 // indirect
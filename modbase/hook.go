@@ -1,11 +1,14 @@
 package modbase
 
 import (
+	"time"
+
 	"github.com/Wariie/go-woxy/com"
 	"github.com/gin-gonic/gin"
 )
 
 func cmd(c *gin.Context) {
+	start := time.Now()
 	t, b := com.GetCustomRequestType(c.Request)
 
 	mod := GetModManager().GetMod()
@@ -14,7 +17,7 @@ func cmd(c *gin.Context) {
 	var err error
 
 	if t["Hash"] != mod.Hash {
-		response = "Error reading module Hash"
+		err = com.NewCommandError(com.CodeUnauthorized, "Error reading module Hash")
 	} else {
 		switch t["Type"] {
 		case "Command":
@@ -31,21 +34,26 @@ func cmd(c *gin.Context) {
 			case "Ping":
 				response, err = ping(&p, c, mod)
 			default:
-				for k := range mod.CustomCommands {
-					if k == sr.Command {
-
-						response, err = mod.CustomCommands[k](&p, c, mod)
-						break
+				if run, ok := mod.CustomCommands[sr.Command]; ok {
+					//CHECK ARGUMENTS OF COMMANDS SET WITH SPEC
+					if spec, ok := mod.CommandSpecs[sr.Command]; ok {
+						sr.Args, err = spec.Validate(sr.Args)
+					}
+					if err == nil {
+						response, err = run(&p, c, mod)
 					}
+				} else {
+					err = com.NewCommandError(com.CodeNotFound, "command "+sr.Command+" not found")
 				}
 			}
+		default:
+			err = com.NewCommandError(com.CodeBadRequest, "Unknown request type "+t["Type"])
 		}
-
-	}
-	if err != nil {
-		response += err.Error()
 	}
-	c.String(200, response)
+
+	res := com.NewCommandResult(t["Command"], response, err, time.Since(start))
+	res.Module = mod.Name
+	c.JSON(200, res)
 }
 
 func shutdown(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error) {
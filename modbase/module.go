@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/foolin/goview/supports/ginview"
-	"github.com/gin-contrib/logger"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
@@ -39,7 +38,8 @@ type (
 		Stop()
 		SetServer()
 		SetHubServer()
-		SetCommand(string, func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error))
+		SetCommand(string, func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error), ...com.Param)
+		SetCommandSpec(com.CommandSpec, func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error))
 	}
 
 	/*ModuleImpl - Impl of Module*/
@@ -53,6 +53,7 @@ type (
 		Server         com.Server
 		RessourcePath  string
 		CustomCommands map[string]func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error)
+		CommandSpecs   map[string]com.CommandSpec
 	}
 )
 
@@ -61,12 +62,29 @@ func (mod *ModuleImpl) Stop(c *gin.Context) {
 	GetModManager().Shutdown(c)
 }
 
-//SetCommand - set command
-func (mod *ModuleImpl) SetCommand(name string, run func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error)) {
+//SetCommand - set command, with its parameters checked before run
+func (mod *ModuleImpl) SetCommand(name string, run func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error), params ...com.Param) {
+	mod.SetCommandSpec(com.CommandSpec{Name: name, Params: params}, run)
+}
+
+//SetCommandSpec - set command with its description and parameters
+func (mod *ModuleImpl) SetCommandSpec(spec com.CommandSpec, run func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error)) {
 	if mod.CustomCommands == nil {
 		mod.CustomCommands = map[string]func(r *com.Request, c *gin.Context, mod *ModuleImpl) (string, error){}
 	}
-	mod.CustomCommands[name] = run
+	if mod.CommandSpecs == nil {
+		mod.CommandSpecs = map[string]com.CommandSpec{}
+	}
+	mod.CustomCommands[spec.Name] = run
+	mod.CommandSpecs[spec.Name] = spec
+}
+
+//GetArgs - Get arguments of command request, checked against command parameters
+func GetArgs(r *com.Request) com.Args {
+	if cr, ok := (*r).(*com.CommandRequest); ok && cr.Args != nil {
+		return cr.Args
+	}
+	return com.Args{}
 }
 
 //SetServer -
@@ -106,12 +124,13 @@ func (mod *ModuleImpl) Init() {
 	)
 
 	r := gin.New()
-	r.Use(logger.SetLogger(), gin.Recovery())
+	r.Use(RequestID(), accessLogger(), gin.Recovery(), Tracing())
 
 	GetModManager().SetRouter(r)
 	GetModManager().SetMod(mod)
 
 	mod.readSecret()
+	mod.initTracing()
 
 	if mod.RessourcePath == "" {
 		mod.RessourcePath = "ressources/"
@@ -121,6 +140,11 @@ func (mod *ModuleImpl) Init() {
 		mod.Server = com.Server{IP: "0.0.0.0", Port: "4224", Protocol: "http"}
 	}
 
+	//PORT ALLOCATED BY GO-WOXY
+	if p := os.Getenv("WOXY_PORT"); p != "" {
+		mod.Server.Port = p
+	}
+
 	if mod.HubServer == (com.Server{}) {
 		mod.HubServer = com.Server{IP: "0.0.0.0", Port: "2000", Protocol: "http"}
 	}
@@ -184,6 +208,9 @@ func (mod *ModuleImpl) connectToHub() bool {
 	var commands []string
 	for k := range mod.CustomCommands {
 		commands = append(commands, k)
+		if spec, ok := mod.CommandSpecs[k]; ok {
+			cr.CommandSpecs = append(cr.CommandSpecs, spec)
+		}
 	}
 
 	cr.Generate(commands, mod.Name, mod.Server.Port, strconv.Itoa(os.Getpid()), mod.Secret)
@@ -0,0 +1,73 @@
+package modbase
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	zLog "github.com/rs/zerolog/log"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+const requestIDKey = "request_id"
+
+//RequestID - Middleware keeping X-Request-ID forwarded by go-woxy, or generating one, and echoing it
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(com.RequestIDHeader)
+		if !com.ValidRequestID(id) {
+			id = com.NewRequestID()
+		}
+		c.Header(com.RequestIDHeader, id)
+		c.Set(requestIDKey, id)
+		c.Next()
+	}
+}
+
+//GetRequestID - Get id of request
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+//Logger - Get logger tagging lines with id of request, so go-woxy can find them
+func Logger(c *gin.Context) zerolog.Logger {
+	return zLog.Logger.With().Str(requestIDKey, GetRequestID(c)).Logger()
+}
+
+//accessLogger - Middleware logging served requests with their request id, credentials in query are redacted
+func accessLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
+			path = path + "?" + com.RedactQuery(raw)
+		}
+
+		c.Next()
+
+		msg := "Request"
+		if len(c.Errors) > 0 {
+			msg = c.Errors.String()
+		}
+
+		l := Logger(c).With().
+			Int("status", c.Writer.Status()).
+			Str("method", c.Request.Method).
+			Str("path", path).
+			Str("ip", c.ClientIP()).
+			Dur("latency", time.Since(start)).
+			Str("user-agent", c.Request.UserAgent()).
+			Logger()
+
+		switch {
+		case c.Writer.Status() >= http.StatusInternalServerError:
+			l.Error().Msg(msg)
+		case c.Writer.Status() >= http.StatusBadRequest:
+			l.Warn().Msg(msg)
+		default:
+			l.Info().Msg(msg)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package modbase
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Wariie/go-woxy/com"
+)
+
+const spanKey = "woxy-span"
+
+//initTracing - Export module spans where go-woxy asked to
+func (mod *ModuleImpl) initTracing() {
+	if err := com.InitTracing(mod.Name, os.Getenv("WOXY_TRACE_EXPORTER"), os.Getenv("WOXY_TRACE_ENDPOINT")); err != nil {
+		log.Println("Error starting tracing :", err)
+	}
+}
+
+//Tracing - Middleware continuing trace of go-woxy proxy hop or command
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := com.StartSpan(c.Request.Method+" "+c.FullPath(), com.SpanServer, c.GetHeader(com.TraceHeader))
+		span.Set("http.method", c.Request.Method)
+		span.Set("http.route", c.FullPath())
+		span.Set("http.request_id", GetRequestID(c))
+
+		//HANDLERS FORWARDING THE REQUEST PROPAGATE MODULE SPAN
+		c.Request.Header.Set(com.TraceHeader, span.Context().Traceparent())
+		c.Set(spanKey, span)
+
+		c.Next()
+
+		var err error
+		status := c.Writer.Status()
+		span.Set("http.status_code", strconv.Itoa(status))
+		if len(c.Errors) > 0 {
+			err = c.Errors.Last()
+		} else if status >= 500 {
+			err = errors.New(http.StatusText(status))
+		}
+		span.Finish(err)
+	}
+}
+
+//GetSpan - Get span of request, to start child spans with its context
+func GetSpan(c *gin.Context) *com.Span {
+	if s, ok := c.Get(spanKey); ok {
+		return s.(*com.Span)
+	}
+	return nil
+}